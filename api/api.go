@@ -1,7 +1,12 @@
 package api
 
 import (
+	"github.com/aweris/stp/internal/audit"
+	"github.com/aweris/stp/internal/inventory"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/sales"
 	"github.com/aweris/stp/internal/server"
+	"github.com/aweris/stp/internal/taxes"
 	"github.com/gorilla/mux"
 	"net/http"
 	"time"
@@ -22,11 +27,58 @@ func NewHandler(s *server.Server) *ApiHandler {
 	api.registerHealthCheck()
 	api.registerInventoryRoutes()
 	api.registerTaxRoutes()
+	api.registerZoneRoutes()
+	api.registerDiscountRoutes()
+	api.registerCouponRoutes()
 	api.registerSalesRoutes()
 
 	return api
 }
 
 func (ah *ApiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// user making the request is kept in context for auditing changes
+	if user := r.Header.Get("X-User"); user != "" {
+		r = r.WithContext(audit.WithActor(r.Context(), user))
+	}
+
 	ah.router.ServeHTTP(w, r)
 }
+
+// errorStatus returns http status code for an error returned from services, invalid values sent by client are
+// bad requests and everything else is internal server error
+func errorStatus(err error) int {
+	if _, ok := err.(*models.InvalidValueError); ok {
+		return http.StatusBadRequest
+	}
+
+	switch err {
+	case taxes.ErrInvalidTaxName, taxes.ErrInvalidTaxRate, taxes.ErrInvalidTaxKind, taxes.ErrInvalidTaxAmount,
+		taxes.ErrInvalidTaxRounding, taxes.ErrInvalidTaxValidity, taxes.ErrInvalidTaxRule, taxes.ErrInvalidTaxCategory,
+		taxes.ErrInvalidTaxOrigin, taxes.ErrInvalidTaxCondition:
+		return http.StatusBadRequest
+	case taxes.ErrInvalidTaxId, taxes.ErrInvalidZoneId, taxes.ErrInvalidZoneName:
+		return http.StatusBadRequest
+	case inventory.ErrInvalidItemName, inventory.ErrInvalidItemPrice, inventory.ErrInvalidItemOrigin:
+		return http.StatusBadRequest
+	case inventory.ErrInvalidCategoryId, inventory.ErrInvalidItemId, sales.ErrInvalidItemCount, sales.ErrNotItemInBasket:
+		return http.StatusBadRequest
+	case sales.ErrInvalidDiscountName, sales.ErrInvalidDiscountKind, sales.ErrInvalidDiscountRate,
+		sales.ErrInvalidDiscountAmount, sales.ErrInvalidDiscountScope, sales.ErrInvalidDiscountQuantity:
+		return http.StatusBadRequest
+	case sales.ErrInvalidCouponCode, sales.ErrInvalidCouponDiscount, sales.ErrInvalidCouponLimit,
+		sales.ErrCouponExpired, sales.ErrCouponUsedUp, sales.ErrCouponAlreadyApplied:
+		return http.StatusBadRequest
+	case sales.ErrInvalidCertificate, sales.ErrCertificateExpired:
+		return http.StatusBadRequest
+	case sales.ErrInvalidReceiptRange:
+		return http.StatusBadRequest
+	case sales.ErrCouponNotFound:
+		return http.StatusNotFound
+	case taxes.ErrTaxConflict, taxes.ErrZoneInUse, inventory.ErrCategoryNotEmpty, inventory.ErrCategoryInUse:
+		return http.StatusConflict
+	case sales.ErrBasketConflict, sales.ErrBasketNotOpen, sales.ErrBasketNotClosed:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
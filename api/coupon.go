@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aweris/stp/internal/models"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func (ah *ApiHandler) registerCouponRoutes() {
+	sub := ah.router.PathPrefix("/sales/coupon").Subrouter()
+
+	sub.HandleFunc("", ah.createCouponHandler).Methods("PUT")
+	sub.HandleFunc("", ah.fetchCouponHandler).Methods("GET")
+	sub.HandleFunc("/{code}", ah.deleteCouponHandler).Methods("DELETE")
+	sub.HandleFunc("/{code}", ah.getCouponByCodeHandler).Methods("GET")
+}
+
+func (ah *ApiHandler) createCouponHandler(w http.ResponseWriter, r *http.Request) {
+	var c models.Coupon
+	if r.Body == nil {
+		http.Error(w, "Please send a request body", 400)
+		return
+	}
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	nc, err := ah.server.SaleService.CreateCoupon(r.Context(), &c)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nc)
+}
+
+func (ah *ApiHandler) fetchCouponHandler(w http.ResponseWriter, r *http.Request) {
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	coupons, err := ah.server.SaleService.FetchAllCoupons(r.Context())
+
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(coupons)
+}
+
+func (ah *ApiHandler) deleteCouponHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	code := vars[`code`]
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	c, err := ah.server.SaleService.DeleteCoupon(r.Context(), code)
+
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if c == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+func (ah *ApiHandler) getCouponByCodeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	code := vars[`code`]
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	c, err := ah.server.SaleService.GetCouponByCode(r.Context(), code)
+
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if c == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aweris/stp/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"net/http"
+)
+
+func (ah *ApiHandler) registerDiscountRoutes() {
+	sub := ah.router.PathPrefix("/sales/discount").Subrouter()
+
+	sub.HandleFunc("", ah.createDiscountHandler).Methods("PUT")
+	sub.HandleFunc("", ah.fetchDiscountHandler).Methods("GET")
+	sub.HandleFunc("/{id}", ah.deleteDiscountHandler).Methods("DELETE")
+	sub.HandleFunc("/{id}", ah.getDiscountByIdHandler).Methods("GET")
+}
+
+func (ah *ApiHandler) createDiscountHandler(w http.ResponseWriter, r *http.Request) {
+	var d models.Discount
+	if r.Body == nil {
+		http.Error(w, "Please send a request body", 400)
+		return
+	}
+	err := json.NewDecoder(r.Body).Decode(&d)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	nd, err := ah.server.SaleService.CreateDiscount(r.Context(), &d)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nd)
+}
+
+func (ah *ApiHandler) fetchDiscountHandler(w http.ResponseWriter, r *http.Request) {
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	discounts, err := ah.server.SaleService.FetchAllDiscounts(r.Context())
+
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discounts)
+}
+
+func (ah *ApiHandler) deleteDiscountHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	discountId := vars[`id`]
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	id, err := uuid.FromString(discountId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+
+	d, err := ah.server.SaleService.DeleteDiscount(r.Context(), id)
+
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if d == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+func (ah *ApiHandler) getDiscountByIdHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	discountId := vars[`id`]
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	id, err := uuid.FromString(discountId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+
+	d, err := ah.server.SaleService.GetDiscountByID(r.Context(), id)
+
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if d == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
@@ -127,7 +127,7 @@ func (ah *ApiHandler) deleteCategoryHandler(w http.ResponseWriter, r *http.Reque
 	t, err := ah.server.InventoryService.DeleteCategory(r.Context(), id)
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -221,7 +221,7 @@ func (ah *ApiHandler) createItemHandler(w http.ResponseWriter, r *http.Request)
 	ni, err := ah.server.InventoryService.CreateItem(r.Context(), &i)
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -250,7 +250,7 @@ func (ah *ApiHandler) updateItemHandler(w http.ResponseWriter, r *http.Request)
 	ui, err := ah.server.InventoryService.UpdateItem(r.Context(), &i)
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -3,9 +3,15 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/sales"
 	"github.com/gorilla/mux"
 	"github.com/satori/go.uuid"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func (ah *ApiHandler) registerSalesRoutes() {
@@ -17,8 +23,12 @@ func (ah *ApiHandler) registerSalesRoutes() {
 	br.HandleFunc("/{id}", ah.getBasketHandler).Methods("GET")
 	br.HandleFunc("/{id}/item", ah.addItemToBasketHandler).Methods("POST")
 	br.HandleFunc("/{id}/item", ah.deleteItemFromBasketHandler).Methods("DELETE")
+	br.HandleFunc("/{id}/certificate", ah.attachCertificateHandler).Methods("POST")
+	br.HandleFunc("/{id}/coupon", ah.applyCouponHandler).Methods("POST")
+	br.HandleFunc("/{id}/reprice", ah.repriceBasketHandler).Methods("POST")
 	br.HandleFunc("/{id}/cancel", ah.cancelBasketHandler).Methods("POST")
 	br.HandleFunc("/{id}/close", ah.closeBasketHandler).Methods("POST")
+	br.HandleFunc("/{id}/receipt", ah.getBasketReceiptHandler).Methods("GET")
 
 	rr := sale.PathPrefix("/receipt").Subrouter()
 
@@ -30,22 +40,60 @@ type BasketDTO struct {
 	Id uuid.UUID `json:"id"`
 }
 
+type CreateBasketDTO struct {
+	ZoneId uuid.UUID `json:"zone_id"`
+}
+
 type BasketItemDTO struct {
 	ItemId uuid.UUID `json:"item_id"`
 	Count  int       `json:"count"`
 }
 
+type CouponDTO struct {
+	Code string `json:"code"`
+}
+
+// basketContext returns request context carrying basket version sent in If-Match header, requests without the header
+// update whatever the current version is
+func basketContext(r *http.Request) (context.Context, error) {
+	match := r.Header.Get("If-Match")
+	if match == "" || match == "*" {
+		return r.Context(), nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(match, "W/"), `"`))
+	if err != nil {
+		return nil, err
+	}
+	return sales.WithBasketVersion(r.Context(), version), nil
+}
+
+// basketETag returns entity tag of the basket version, sent back in If-Match header for updating the basket
+func basketETag(b *models.Basket) string {
+	return strconv.Quote(strconv.Itoa(b.Version))
+}
+
 func (ah *ApiHandler) createBasketHandler(w http.ResponseWriter, r *http.Request) {
+	// request body is optional, basket without zone created when it is missing
+	var dto CreateBasketDTO
+	if r.Body != nil {
+		err := json.NewDecoder(r.Body).Decode(&dto)
+		if err != nil && err != io.EOF {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+
 	// Timeout in context
 	context.WithTimeout(
 		r.Context(),
 		ah.timeout,
 	)
 
-	bid, err := ah.server.SaleService.CreateBasket(r.Context())
+	bid, err := ah.server.SaleService.CreateBasket(r.Context(), dto.ZoneId)
 
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -83,6 +131,7 @@ func (ah *ApiHandler) getBasketHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", basketETag(t))
 	json.NewEncoder(w).Encode(t)
 }
 
@@ -107,19 +156,26 @@ func (ah *ApiHandler) addItemToBasketHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	ctx, err := basketContext(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match header", 400)
+		return
+	}
+
 	// Timeout in context
 	context.WithTimeout(
 		r.Context(),
 		ah.timeout,
 	)
 
-	err = ah.server.SaleService.AddItem(r.Context(), id, b.ItemId, b.Count)
+	basket, err := ah.server.SaleService.AddItem(ctx, id, b.ItemId, b.Count)
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
+	w.Header().Set("ETag", basketETag(basket))
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
@@ -145,23 +201,154 @@ func (ah *ApiHandler) deleteItemFromBasketHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	ctx, err := basketContext(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match header", 400)
+		return
+	}
+
 	// Timeout in context
 	context.WithTimeout(
 		r.Context(),
 		ah.timeout,
 	)
 
-	err = ah.server.SaleService.RemoveItem(r.Context(), id, b.ItemId, b.Count)
+	basket, err := ah.server.SaleService.RemoveItem(ctx, id, b.ItemId, b.Count)
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
+	w.Header().Set("ETag", basketETag(basket))
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
 
+func (ah *ApiHandler) attachCertificateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	basketId := vars[`id`]
+
+	id, err := uuid.FromString(basketId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+	var c models.ExemptionCertificate
+	if r.Body == nil {
+		http.Error(w, "Please send a request body", 400)
+		return
+	}
+	err = json.NewDecoder(r.Body).Decode(&c)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	ctx, err := basketContext(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match header", 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	basket, err := ah.server.SaleService.AttachCertificate(ctx, id, &c)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", basketETag(basket))
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
+
+func (ah *ApiHandler) applyCouponHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	basketId := vars[`id`]
+
+	id, err := uuid.FromString(basketId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+	var dto CouponDTO
+	if r.Body == nil {
+		http.Error(w, "Please send a request body", 400)
+		return
+	}
+	err = json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	ctx, err := basketContext(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match header", 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	basket, err := ah.server.SaleService.ApplyCoupon(ctx, id, dto.Code)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("ETag", basketETag(basket))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ah *ApiHandler) repriceBasketHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	basketId := vars[`id`]
+
+	id, err := uuid.FromString(basketId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+
+	ctx, err := basketContext(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match header", 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	b, err := ah.server.SaleService.Reprice(ctx, id)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", basketETag(b))
+	json.NewEncoder(w).Encode(b)
+}
+
 func (ah *ApiHandler) cancelBasketHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
@@ -173,19 +360,26 @@ func (ah *ApiHandler) cancelBasketHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	ctx, err := basketContext(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match header", 400)
+		return
+	}
+
 	// Timeout in context
 	context.WithTimeout(
 		r.Context(),
 		ah.timeout,
 	)
 
-	err = ah.server.SaleService.CancelBasket(r.Context(), id)
+	basket, err := ah.server.SaleService.CancelBasket(ctx, id)
 
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
+	w.Header().Set("ETag", basketETag(basket))
 	w.WriteHeader(http.StatusNoContent)
 	return
 }
@@ -201,16 +395,22 @@ func (ah *ApiHandler) closeBasketHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	ctx, err := basketContext(r)
+	if err != nil {
+		http.Error(w, "Invalid If-Match header", 400)
+		return
+	}
+
 	// Timeout in context
 	context.WithTimeout(
 		r.Context(),
 		ah.timeout,
 	)
 
-	receipt, err := ah.server.SaleService.CloseBasket(r.Context(), id)
+	receipt, err := ah.server.SaleService.CloseBasket(ctx, id)
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -223,17 +423,49 @@ func (ah *ApiHandler) closeBasketHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(receipt)
 }
 
+// receiptTime parses a bound of receipt date range, either a RFC 3339 time or a date meaning start of the day in UTC.
+// Empty value means the range is not bounded.
+func receiptTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
 func (ah *ApiHandler) fetchAllReceiptsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := receiptTime(query.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from format", 400)
+		return
+	}
+	to, err := receiptTime(query.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to format", 400)
+		return
+	}
+
 	// Timeout in context
 	context.WithTimeout(
 		r.Context(),
 		ah.timeout,
 	)
 
-	receipts, err := ah.server.SaleService.FetchAllReceipts(r.Context())
+	var receipts []*models.Receipt
+	if from.IsZero() && to.IsZero() {
+		receipts, err = ah.server.SaleService.FetchAllReceipts(r.Context())
+	} else {
+		receipts, err = ah.server.SaleService.FetchReceiptsIssuedBetween(r.Context(), from, to)
+	}
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -278,3 +510,36 @@ func (ah *ApiHandler) getReceiptHandler(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(receipt)
 }
+
+func (ah *ApiHandler) getBasketReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	basketId := vars[`id`]
+
+	id, err := uuid.FromString(basketId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	receipt, err := ah.server.SaleService.GetReceiptByBasketID(r.Context(), id)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	if receipt == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipt)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 	"net/http"
+	"time"
 )
 
 func (ah *ApiHandler) registerTaxRoutes() {
@@ -16,17 +17,56 @@ func (ah *ApiHandler) registerTaxRoutes() {
 	sub.HandleFunc("", ah.createTaxHandler).Methods("PUT")
 	sub.HandleFunc("", ah.updateTaxHandler).Methods("POST")
 	sub.HandleFunc("", ah.fetchTaxHandler).Methods("GET")
+	sub.HandleFunc("/conflicts", ah.fetchTaxConflictsHandler).Methods("GET")
 	sub.HandleFunc("/{id}", ah.deleteTaxHandler).Methods("DELETE")
 	sub.HandleFunc("/{id}", ah.getTaxByIdHandler).Methods("GET")
+	sub.HandleFunc("/{id}/schedule", ah.scheduleTaxRateHandler).Methods("POST")
+	sub.HandleFunc("/{id}/history", ah.getTaxHistoryHandler).Methods("GET")
+	sub.HandleFunc("/quote", ah.quoteHandler).Methods("POST")
 }
 
 type TaxDTO struct {
-	Id         uuid.UUID           `json:"id"`
-	Name       string              `json:"name"`
-	Rate       decimal.Decimal     `json:"rate"`
-	Origin     models.TaxOrigin    `json:"origin"`
-	Condition  models.TaxCondition `json:"condition"`
-	Categories []uuid.UUID         `json:"categories"`
+	Id         uuid.UUID            `json:"id"`
+	Name       string               `json:"name"`
+	Kind       models.TaxKind       `json:"kind,omitempty"`
+	Rate       decimal.Decimal      `json:"rate"`
+	Amount     decimal.Decimal      `json:"amount"`
+	Origin     models.TaxOrigin     `json:"origin"`
+	Condition  models.TaxCondition  `json:"condition"`
+	Categories []uuid.UUID          `json:"categories"`
+	Rounding   *models.RoundingRule `json:"rounding,omitempty"`
+	Rule       *models.TaxRule      `json:"rule,omitempty"`
+	Priority   int                  `json:"priority"`
+	Compound   bool                 `json:"compound"`
+	Zones      []uuid.UUID          `json:"zones,omitempty"`
+	ValidFrom  *time.Time           `json:"valid_from,omitempty"`
+	ValidTo    *time.Time           `json:"valid_to,omitempty"`
+}
+
+type TaxVersionDTO struct {
+	TaxId     uuid.UUID        `json:"tax_id"`
+	Version   uint64           `json:"version"`
+	Action    models.TaxAction `json:"action"`
+	ChangedBy string           `json:"changed_by"`
+	ChangedAt time.Time        `json:"changed_at"`
+	Old       *TaxDTO          `json:"old,omitempty"`
+	New       *TaxDTO          `json:"new,omitempty"`
+}
+
+type TaxConflictDTO struct {
+	Tax         *TaxDTO                  `json:"tax"`
+	Conflicting *TaxDTO                  `json:"conflicting"`
+	Reason      models.TaxConflictReason `json:"reason"`
+}
+
+type TaxRateChangeDTO struct {
+	Rate      decimal.Decimal `json:"rate"`
+	ValidFrom time.Time       `json:"valid_from"`
+}
+
+type QuoteRequestDTO struct {
+	ZoneId uuid.UUID           `json:"zone_id"`
+	Items  []*models.QuoteLine `json:"items"`
 }
 
 func fromTaxToDTO(tax *models.Tax) *TaxDTO {
@@ -39,12 +79,40 @@ func fromTaxToDTO(tax *models.Tax) *TaxDTO {
 	return &TaxDTO{
 		Id:         tax.Id,
 		Name:       tax.Name,
+		Kind:       tax.Kind,
 		Rate:       tax.Rate,
+		Amount:     tax.Amount,
 		Origin:     tax.Origin,
 		Condition:  tax.Condition,
 		Categories: categories,
+		Rounding:   tax.Rounding,
+		Rule:       tax.Rule,
+		Priority:   tax.Priority,
+		Compound:   tax.Compound,
+		Zones:      tax.Zones,
+		ValidFrom:  tax.ValidFrom,
+		ValidTo:    tax.ValidTo,
+	}
+
+}
+
+func fromTaxVersionToDTO(version *models.TaxVersion) *TaxVersionDTO {
+	dto := &TaxVersionDTO{
+		TaxId:     version.TaxId,
+		Version:   version.Version,
+		Action:    version.Action,
+		ChangedBy: version.ChangedBy,
+		ChangedAt: version.ChangedAt,
+	}
+
+	if version.Old != nil {
+		dto.Old = fromTaxToDTO(version.Old)
+	}
+	if version.New != nil {
+		dto.New = fromTaxToDTO(version.New)
 	}
 
+	return dto
 }
 
 func (t *TaxDTO) toTax() *models.Tax {
@@ -56,10 +124,19 @@ func (t *TaxDTO) toTax() *models.Tax {
 	return &models.Tax{
 		Id:         t.Id,
 		Name:       t.Name,
+		Kind:       t.Kind,
 		Rate:       t.Rate,
+		Amount:     t.Amount,
 		Origin:     t.Origin,
 		Condition:  t.Condition,
 		Categories: categories,
+		Rounding:   t.Rounding,
+		Rule:       t.Rule,
+		Priority:   t.Priority,
+		Compound:   t.Compound,
+		Zones:      t.Zones,
+		ValidFrom:  t.ValidFrom,
+		ValidTo:    t.ValidTo,
 	}
 }
 
@@ -84,7 +161,7 @@ func (ah *ApiHandler) createTaxHandler(w http.ResponseWriter, r *http.Request) {
 	nt, err := ah.server.TaxService.CreateTax(r.Context(), dto.toTax())
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -113,7 +190,7 @@ func (ah *ApiHandler) updateTaxHandler(w http.ResponseWriter, r *http.Request) {
 	nt, err := ah.server.TaxService.UpdateTax(r.Context(), dto.toTax())
 
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -210,3 +287,128 @@ func (ah *ApiHandler) getTaxByIdHandler(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(fromTaxToDTO(t))
 }
+
+func (ah *ApiHandler) scheduleTaxRateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	taxId := vars[`id`]
+
+	id, err := uuid.FromString(taxId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+	var dto TaxRateChangeDTO
+	if r.Body == nil {
+		http.Error(w, "Please send a request body", 400)
+		return
+	}
+	err = json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	nt, err := ah.server.TaxService.ScheduleRateChange(r.Context(), id, dto.Rate, dto.ValidFrom)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fromTaxToDTO(nt))
+}
+
+func (ah *ApiHandler) getTaxHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	taxId := vars[`id`]
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	id, err := uuid.FromString(taxId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 400)
+		return
+	}
+
+	versions, err := ah.server.TaxService.GetTaxHistory(r.Context(), id)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	result := make([]*TaxVersionDTO, 0, len(versions))
+
+	for _, v := range versions {
+		result = append(result, fromTaxVersionToDTO(v))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (ah *ApiHandler) quoteHandler(w http.ResponseWriter, r *http.Request) {
+	var dto QuoteRequestDTO
+	if r.Body == nil {
+		http.Error(w, "Please send a request body", 400)
+		return
+	}
+	err := json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	quote, err := ah.server.SaleService.Quote(r.Context(), dto.ZoneId, dto.Items)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
+func (ah *ApiHandler) fetchTaxConflictsHandler(w http.ResponseWriter, r *http.Request) {
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	conflicts, err := ah.server.TaxService.FindConflicts(r.Context())
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	result := make([]*TaxConflictDTO, 0, len(conflicts))
+
+	for _, v := range conflicts {
+		result = append(result, &TaxConflictDTO{Tax: fromTaxToDTO(v.Tax), Conflicting: fromTaxToDTO(v.Conflicting), Reason: v.Reason})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aweris/stp/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+	"net/http"
+)
+
+func (ah *ApiHandler) registerZoneRoutes() {
+	sub := ah.router.PathPrefix("/zones").Subrouter()
+
+	sub.HandleFunc("", ah.createZoneHandler).Methods("PUT")
+	sub.HandleFunc("", ah.updateZoneHandler).Methods("POST")
+	sub.HandleFunc("", ah.fetchZoneHandler).Methods("GET")
+	sub.HandleFunc("/{id}", ah.deleteZoneHandler).Methods("DELETE")
+	sub.HandleFunc("/{id}", ah.getZoneByIdHandler).Methods("GET")
+}
+
+func (ah *ApiHandler) createZoneHandler(w http.ResponseWriter, r *http.Request) {
+	var z models.Zone
+	if r.Body == nil {
+		http.Error(w, "Please send a request body", 400)
+		return
+	}
+	err := json.NewDecoder(r.Body).Decode(&z)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	nz, err := ah.server.TaxService.CreateZone(r.Context(), &z)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nz)
+}
+
+func (ah *ApiHandler) updateZoneHandler(w http.ResponseWriter, r *http.Request) {
+	var z models.Zone
+	if r.Body == nil {
+		http.Error(w, "Please send a request body", 400)
+		return
+	}
+	err := json.NewDecoder(r.Body).Decode(&z)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	uz, err := ah.server.TaxService.UpdateZone(r.Context(), &z)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(uz)
+}
+
+func (ah *ApiHandler) fetchZoneHandler(w http.ResponseWriter, r *http.Request) {
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	zones, err := ah.server.TaxService.FetchAllZones(r.Context())
+
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zones)
+}
+
+func (ah *ApiHandler) deleteZoneHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	zoneId := vars[`id`]
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	id, err := uuid.FromString(zoneId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+
+	z, err := ah.server.TaxService.DeleteZone(r.Context(), id)
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	if z == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(z)
+}
+
+func (ah *ApiHandler) getZoneByIdHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	zoneId := vars[`id`]
+
+	// Timeout in context
+	context.WithTimeout(
+		r.Context(),
+		ah.timeout,
+	)
+
+	id, err := uuid.FromString(zoneId)
+	if err != nil {
+		http.Error(w, "Invalid id format", 500)
+		return
+	}
+
+	z, err := ah.server.TaxService.GetZoneByID(r.Context(), id)
+
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if z == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(z)
+}
@@ -91,7 +91,7 @@ func LoadTestData(server *server.Server) {
 	}
 	server.InventoryService.CreateItem(context.Background(), c1Chocolate)
 
-	bid1, _ := server.SaleService.CreateBasket(context.Background())
+	bid1, _ := server.SaleService.CreateBasket(context.Background(), uuid.Nil)
 
 	server.SaleService.AddItem(context.Background(), bid1, c1Book.Id, 2)
 	server.SaleService.AddItem(context.Background(), bid1, c1CD.Id, 1)
@@ -120,7 +120,7 @@ func LoadTestData(server *server.Server) {
 	}
 	server.InventoryService.CreateItem(context.Background(), c2Perfume)
 
-	bid2, _ := server.SaleService.CreateBasket(context.Background())
+	bid2, _ := server.SaleService.CreateBasket(context.Background(), uuid.Nil)
 
 	server.SaleService.AddItem(context.Background(), bid2, c2Chocolate.Id, 1)
 	server.SaleService.AddItem(context.Background(), bid2, c2Perfume.Id, 1)
@@ -165,7 +165,7 @@ func LoadTestData(server *server.Server) {
 	}
 	server.InventoryService.CreateItem(context.Background(), c3Chocolate)
 
-	bid3, _ := server.SaleService.CreateBasket(context.Background())
+	bid3, _ := server.SaleService.CreateBasket(context.Background(), uuid.Nil)
 
 	server.SaleService.AddItem(context.Background(), bid3, c3PerfumeImport.Id, 1)
 	server.SaleService.AddItem(context.Background(), bid3, c3PerfumeLocal.Id, 1)
@@ -0,0 +1,22 @@
+package audit
+
+import "context"
+
+// SystemActor is used when there is no actor in context, e.g. changes made by application itself
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// WithActor returns a copy of context carrying the actor who makes the changes
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by context or SystemActor if context doesn't have one
+func ActorFromContext(ctx context.Context) string {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	if !ok || actor == "" {
+		return SystemActor
+	}
+	return actor
+}
@@ -8,6 +8,7 @@ var (
 	ErrInvalidCategoryId   = errors.New("invalid category id")
 	ErrInvalidCategoryName = errors.New("invalid category name")
 	ErrCategoryNotEmpty    = errors.New("category is not empty")
+	ErrCategoryInUse       = errors.New("category is used by tax")
 
 	ErrInvalidItemId     = errors.New("invalid item id")
 	ErrInvalidItemName   = errors.New("invalid item name")
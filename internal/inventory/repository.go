@@ -6,6 +6,11 @@ import (
 	"github.com/satori/go.uuid"
 )
 
+// Transactor runs changes of repositories in a single transaction, repositories join it through the given context
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type CategoryRepository interface {
 	SaveCategory(ctx context.Context, cat *models.Category) (*models.Category, error)
 	GetCategoryByID(ctx context.Context, categoryId uuid.UUID) (*models.Category, error)
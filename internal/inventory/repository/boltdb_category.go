@@ -62,7 +62,7 @@ func NewBoltDBCategoryRepository(db *storage.BoltDB) inventory.CategoryRepositor
 
 // SaveCategory adding or updating category and related indexes without checking existing value.
 func (bcr *boltDBCategoryRepository) SaveCategory(ctx context.Context, cat *models.Category) (*models.Category, error) {
-	err := bcr.db.Update(func(tx *bolt.Tx) error {
+	err := bcr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketCategory))
 
 		data, err := json.Marshal(cat)
@@ -90,7 +90,7 @@ func (bcr *boltDBCategoryRepository) SaveCategory(ctx context.Context, cat *mode
 // GetCategoryByID responsible for fetching category with id
 func (bcr *boltDBCategoryRepository) GetCategoryByID(ctx context.Context, categoryId uuid.UUID) (*models.Category, error) {
 	var t *models.Category
-	err := bcr.db.View(func(tx *bolt.Tx) error {
+	err := bcr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketCategory))
 
 		v := tb.Get(categoryId.Bytes())
@@ -109,7 +109,7 @@ func (bcr *boltDBCategoryRepository) GetCategoryByID(ctx context.Context, catego
 // GetCategoryByName responsible for fetching category with name(Name is case insensitive).
 func (bcr *boltDBCategoryRepository) GetCategoryByName(ctx context.Context, categoryName string) (*models.Category, error) {
 	var t *models.Category
-	err := bcr.db.View(func(tx *bolt.Tx) error {
+	err := bcr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketCategory))
 
 		mb := tb.Bucket([]byte(bucketCategoryMeta))
@@ -137,7 +137,7 @@ func (bcr *boltDBCategoryRepository) GetCategoryByName(ctx context.Context, cate
 // FetchAllCategories fetching all categories
 func (bcr *boltDBCategoryRepository) FetchAllCategories(ctx context.Context) ([]*models.Category, error) {
 	var categories = make([]*models.Category, 0)
-	err := bcr.db.View(func(tx *bolt.Tx) error {
+	err := bcr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketCategory))
 
 		return tb.ForEach(func(k, v []byte) error {
@@ -159,7 +159,7 @@ func (bcr *boltDBCategoryRepository) FetchAllCategories(ctx context.Context) ([]
 // DeleteCategory deletes category with id
 func (bcr *boltDBCategoryRepository) DeleteCategory(ctx context.Context, categoryId uuid.UUID) (*models.Category, error) {
 	var existing *models.Category
-	err := bcr.db.Update(func(tx *bolt.Tx) error {
+	err := bcr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketCategory))
 
 		v := tb.Get(categoryId.Bytes())
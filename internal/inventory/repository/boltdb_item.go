@@ -61,7 +61,7 @@ func NewBoltDBItemRepository(db *storage.BoltDB) inventory.ItemRepository {
 }
 
 func (bir *boltDBItemRepository) SaveItem(ctx context.Context, i *models.InventoryItem) (*models.InventoryItem, error) {
-	err := bir.db.Update(func(tx *bolt.Tx) error {
+	err := bir.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketItem))
 
 		data, err := json.Marshal(i)
@@ -95,7 +95,7 @@ func (bir *boltDBItemRepository) SaveItem(ctx context.Context, i *models.Invento
 
 func (bir *boltDBItemRepository) GetItemByID(ctx context.Context, itemId uuid.UUID) (*models.InventoryItem, error) {
 	var i *models.InventoryItem
-	err := bir.db.View(func(tx *bolt.Tx) error {
+	err := bir.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketItem))
 
 		v := tb.Get(itemId.Bytes())
@@ -113,7 +113,7 @@ func (bir *boltDBItemRepository) GetItemByID(ctx context.Context, itemId uuid.UU
 
 func (bir *boltDBItemRepository) GetItemsByCategoryID(ctx context.Context, categoryId uuid.UUID) ([]*models.InventoryItem, error) {
 	var items = make([]*models.InventoryItem, 0)
-	err := bir.db.View(func(tx *bolt.Tx) error {
+	err := bir.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketItem))
 
 		// getting index bucket
@@ -146,7 +146,7 @@ func (bir *boltDBItemRepository) GetItemsByCategoryID(ctx context.Context, categ
 
 func (bir *boltDBItemRepository) FetchAllItems(ctx context.Context) ([]*models.InventoryItem, error) {
 	var items = make([]*models.InventoryItem, 0)
-	err := bir.db.View(func(tx *bolt.Tx) error {
+	err := bir.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketItem))
 
 		return tb.ForEach(func(k, v []byte) error {
@@ -167,7 +167,7 @@ func (bir *boltDBItemRepository) FetchAllItems(ctx context.Context) ([]*models.I
 
 func (bir *boltDBItemRepository) DeleteItem(ctx context.Context, itemId uuid.UUID) (*models.InventoryItem, error) {
 	var existing *models.InventoryItem
-	err := bir.db.Update(func(tx *bolt.Tx) error {
+	err := bir.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketItem))
 
 		v := tb.Get(itemId.Bytes())
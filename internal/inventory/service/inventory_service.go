@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/aweris/stp/internal/inventory"
 	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/taxes"
 	"github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -11,11 +12,15 @@ import (
 type inventoryService struct {
 	itemRepo     inventory.ItemRepository
 	categoryRepo inventory.CategoryRepository
+
+	taxService taxes.TaxService
+
+	transactor inventory.Transactor
 }
 
-// NewInventoryService creates inventory service with given repository interfaces
-func NewInventoryService(itemRepo inventory.ItemRepository, categoryRepo inventory.CategoryRepository) inventory.InventoryService {
-	return &inventoryService{itemRepo: itemRepo, categoryRepo: categoryRepo}
+// NewInventoryService creates inventory service with given repository interfaces, tax service and transactor
+func NewInventoryService(itemRepo inventory.ItemRepository, categoryRepo inventory.CategoryRepository, taxService taxes.TaxService, transactor inventory.Transactor) inventory.InventoryService {
+	return &inventoryService{itemRepo: itemRepo, categoryRepo: categoryRepo, taxService: taxService, transactor: transactor}
 }
 
 func (is *inventoryService) CreateCategory(ctx context.Context, cat *models.Category) (*models.Category, error) {
@@ -120,31 +125,53 @@ func (is *inventoryService) DeleteCategory(ctx context.Context, categoryId uuid.
 		return nil, inventory.ErrInvalidCategoryId
 	}
 
-	exist, err := is.categoryRepo.GetCategoryByID(ctx, categoryId)
-	if err != nil {
-		log.WithFields(log.Fields{"categoryId": categoryId}).WithError(err).Error("failed to find category")
-		return nil, err
-	}
-	if exist == nil {
-		log.WithFields(log.Fields{"categoryId": categoryId}).WithError(inventory.ErrInvalidCategoryId).Error("failed category id with given id")
-		return nil, inventory.ErrInvalidCategoryId
-	}
+	var deleted *models.Category
 
-	items, err := is.itemRepo.GetItemsByCategoryID(ctx, categoryId)
-	if err != nil {
-		log.WithFields(log.Fields{"categoryId": categoryId}).WithError(err).Error("failed to find category")
-		return nil, err
-	}
-	if len(items) > 0 {
-		log.WithFields(log.Fields{"categoryId": categoryId}).WithError(inventory.ErrCategoryNotEmpty).Error("couldn't delete category")
-		return nil, inventory.ErrCategoryNotEmpty
-	}
+	// category checked and deleted in the same transaction, so no item or tax can refer it in between
+	err := is.transactor.RunInTransaction(ctx, func(ctx context.Context) error {
+		exist, err := is.categoryRepo.GetCategoryByID(ctx, categoryId)
+		if err != nil {
+			log.WithFields(log.Fields{"categoryId": categoryId}).WithError(err).Error("failed to find category")
+			return err
+		}
+		if exist == nil {
+			log.WithFields(log.Fields{"categoryId": categoryId}).WithError(inventory.ErrInvalidCategoryId).Error("failed category id with given id")
+			return inventory.ErrInvalidCategoryId
+		}
+
+		items, err := is.itemRepo.GetItemsByCategoryID(ctx, categoryId)
+		if err != nil {
+			log.WithFields(log.Fields{"categoryId": categoryId}).WithError(err).Error("failed to find category")
+			return err
+		}
+		if len(items) > 0 {
+			log.WithFields(log.Fields{"categoryId": categoryId}).WithError(inventory.ErrCategoryNotEmpty).Error("couldn't delete category")
+			return inventory.ErrCategoryNotEmpty
+		}
+
+		txs, err := is.taxService.FetchAllTaxes(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{"categoryId": categoryId}).WithError(err).Error("failed to fetch taxes")
+			return err
+		}
+		for _, tax := range txs {
+			if _, ok := tax.Categories[categoryId]; ok {
+				log.WithFields(log.Fields{"categoryId": categoryId, "tax": tax}).WithError(inventory.ErrCategoryInUse).Error("couldn't delete category")
+				return inventory.ErrCategoryInUse
+			}
+		}
 
-	deleted, err := is.categoryRepo.DeleteCategory(ctx, categoryId)
+		deleted, err = is.categoryRepo.DeleteCategory(ctx, categoryId)
+		if err != nil {
+			log.WithFields(log.Fields{"categoryId": categoryId}).WithError(err).Error("failed to delete category")
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.WithFields(log.Fields{"categoryId": categoryId}).WithError(err).Error("failed to delete category")
 		return nil, err
 	}
+
 	log.WithFields(log.Fields{"categoryId": categoryId}).Info("category deleted")
 	return deleted, nil
 }
@@ -162,6 +189,10 @@ func (is *inventoryService) CreateItem(ctx context.Context, i *models.InventoryI
 		log.WithFields(log.Fields{"item": i}).WithError(inventory.ErrInvalidItemPrice).Error("invalid item price")
 		return nil, inventory.ErrInvalidItemPrice
 	}
+	if !i.Origin.IsValid() {
+		log.WithFields(log.Fields{"item": i}).WithError(inventory.ErrInvalidItemOrigin).Error("invalid item origin")
+		return nil, inventory.ErrInvalidItemOrigin
+	}
 
 	if i.Id != uuid.Nil {
 		exist, err := is.itemRepo.GetItemByID(ctx, i.Id)
@@ -213,6 +244,10 @@ func (is *inventoryService) UpdateItem(ctx context.Context, i *models.InventoryI
 		log.WithFields(log.Fields{"item": i}).WithError(inventory.ErrInvalidItemPrice).Error("invalid item price")
 		return nil, inventory.ErrInvalidItemPrice
 	}
+	if !i.Origin.IsValid() {
+		log.WithFields(log.Fields{"item": i}).WithError(inventory.ErrInvalidItemOrigin).Error("invalid item origin")
+		return nil, inventory.ErrInvalidItemOrigin
+	}
 
 	exist, err := is.itemRepo.GetItemByID(ctx, i.Id)
 	if err != nil {
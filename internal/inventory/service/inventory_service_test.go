@@ -6,10 +6,14 @@ import (
 	inventoryRepo "github.com/aweris/stp/internal/inventory/repository"
 	inventoryService "github.com/aweris/stp/internal/inventory/service"
 	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/taxes"
+	taxRepo "github.com/aweris/stp/internal/taxes/repository"
+	taxService "github.com/aweris/stp/internal/taxes/service"
 	"github.com/aweris/stp/storage"
 	"github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
 )
 
@@ -17,6 +21,8 @@ type mockedService struct {
 	inventory.InventoryService
 
 	db *storage.TestDB
+
+	ts taxes.TaxService
 }
 
 func newMockedService() *mockedService {
@@ -24,10 +30,14 @@ func newMockedService() *mockedService {
 
 	cr := inventoryRepo.NewBoltDBCategoryRepository(db.BoltDB)
 	ir := inventoryRepo.NewBoltDBItemRepository(db.BoltDB)
+	tr := taxRepo.NewBoltDBTaxRepository(db.BoltDB)
+	thr := taxRepo.NewBoltDBTaxHistoryRepository(db.BoltDB)
+	zr := taxRepo.NewBoltDBZoneRepository(db.BoltDB)
 
-	is := inventoryService.NewInventoryService(ir, cr)
+	ts := taxService.NewTaxService(tr, thr, zr, cr, db.BoltDB, models.TaxCalculationPerTax)
+	is := inventoryService.NewInventoryService(ir, cr, ts, db.BoltDB)
 
-	return &mockedService{db: db, InventoryService: is}
+	return &mockedService{db: db, InventoryService: is, ts: ts}
 }
 
 func (ms *mockedService) Close() {
@@ -316,6 +326,75 @@ func TestInventoryService_DeleteCategory_WhenHasItems_ShouldReturnError(t *testi
 	assert.Equal(t, err, inventory.ErrCategoryNotEmpty, "expecting error")
 }
 
+func TestInventoryService_DeleteCategory_WhenTaxRefersCategory_ShouldReturnError(t *testing.T) {
+	is := newMockedService()
+	defer is.Close()
+
+	c := &models.Category{
+		Id:   uuid.NewV1(),
+		Name: "Test Category",
+	}
+	c, err := is.CreateCategory(context.Background(), c)
+	assert.NoError(t, err, "failed to add category")
+
+	tax := &models.Tax{
+		Id:         uuid.NewV1(),
+		Name:       "Test Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.ExemptToTax,
+		Categories: map[uuid.UUID]bool{c.Id: true},
+	}
+	_, err = is.ts.CreateTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	_, err = is.DeleteCategory(context.Background(), c.Id)
+	assert.Equal(t, err, inventory.ErrCategoryInUse, "expecting error")
+
+	find, err := is.GetCategoryByID(context.Background(), c.Id)
+	assert.NoError(t, err)
+	assert.NotNil(t, find)
+}
+
+func TestInventoryService_DeleteCategory_WhenTaxCreatedConcurrently_ThenOnlyOneShouldSucceed(t *testing.T) {
+	is := newMockedService()
+	defer is.Close()
+
+	for n := 0; n < 10; n++ {
+		c, err := is.CreateCategory(context.Background(), &models.Category{Name: uuid.NewV1().String()})
+		assert.NoError(t, err, "failed to add category")
+
+		tax := &models.Tax{
+			Name:       uuid.NewV1().String(),
+			Rate:       decimal.NewFromFloat32(10),
+			Origin:     models.TaxOriginAll,
+			Condition:  models.SubjectToTax,
+			Categories: map[uuid.UUID]bool{c.Id: true},
+		}
+
+		var wg sync.WaitGroup
+		var taxErr, deleteErr error
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, taxErr = is.ts.CreateTax(context.Background(), tax)
+		}()
+		go func() {
+			defer wg.Done()
+			_, deleteErr = is.DeleteCategory(context.Background(), c.Id)
+		}()
+		wg.Wait()
+
+		if taxErr == nil {
+			assert.Equal(t, inventory.ErrCategoryInUse, deleteErr)
+		} else {
+			assert.Equal(t, taxes.ErrInvalidTaxCategory, taxErr)
+			assert.NoError(t, deleteErr)
+		}
+	}
+}
+
 func TestInventoryService_CreateItem_ShouldCreateItem(t *testing.T) {
 	is := newMockedService()
 	defer is.Close()
@@ -338,6 +417,26 @@ func TestInventoryService_CreateItem_ShouldCreateItem(t *testing.T) {
 	assert.NotNil(t, i)
 }
 
+func TestInventoryService_CreateItem_WhenOriginIsMissing_ThenShouldReturnError(t *testing.T) {
+	is := newMockedService()
+	defer is.Close()
+
+	c := &models.Category{
+		Id:   uuid.NewV1(),
+		Name: "Test Category",
+	}
+	c, err := is.CreateCategory(context.Background(), c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item - 1",
+		CategoryId: c.Id,
+		Price:      decimal.NewFromFloat32(10),
+	}
+	_, err = is.CreateItem(context.Background(), i)
+	assert.Equal(t, err, inventory.ErrInvalidItemOrigin, "expecting error")
+}
+
 func TestInventoryService_CreateItem_WhenItemIsNil_ThenShouldReturnError(t *testing.T) {
 	is := newMockedService()
 	defer is.Close()
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+// DiscountKind is defines how amount of a discount calculated
+type DiscountKind string
+
+const (
+	DiscountKindPercentage DiscountKind = "PERCENTAGE" // refers to discount of rate percent of price
+	DiscountKindFixed      DiscountKind = "FIXED"      // refers to discount of a fixed amount, per unit for item and category discounts
+)
+
+// DiscountScope is defines which part of a basket a discount applies to
+type DiscountScope string
+
+const (
+	DiscountScopeItem     DiscountScope = "ITEM"        // refers to units of the item
+	DiscountScopeCategory DiscountScope = "CATEGORY"    // refers to units of items in the category
+	DiscountScopeBasket   DiscountScope = "BASKET"      // refers to basket total after item and category discounts
+	DiscountScopeBuyXGetY DiscountScope = "BUY_X_GET_Y" // refers to get units of the item are free for every buy units
+)
+
+// DiscountTaxPolicy is defines whether taxes of a sale calculated on prices before or after discounts. Discounts are
+// always calculated on shelf prices, which include taxes of tax inclusive items.
+type DiscountTaxPolicy string
+
+const (
+	DiscountTaxBeforeDiscount DiscountTaxPolicy = "BEFORE_DISCOUNT" // refers to taxes calculated on listed prices, discounts only reduce the amount paid
+	DiscountTaxAfterDiscount  DiscountTaxPolicy = "AFTER_DISCOUNT"  // refers to taxes calculated on discounted prices
+)
+
+// Discount is a promotion reducing price of basket items
+type Discount struct {
+	Id         uuid.UUID       `json:"id"`
+	Name       string          `json:"name"`
+	Kind       DiscountKind    `json:"kind"` // not used by buy x get y discounts
+	Scope      DiscountScope   `json:"scope"`
+	Rate       decimal.Decimal `json:"rate"`
+	Amount     decimal.Decimal `json:"amount"`
+	ItemId     uuid.UUID       `json:"item_id"`     // item of item and buy x get y discounts
+	CategoryId uuid.UUID       `json:"category_id"` // category of category discounts
+	Buy        int             `json:"buy,omitempty"`
+	Get        int             `json:"get,omitempty"`
+	CouponOnly bool            `json:"coupon_only,omitempty"` // applied only to baskets with a coupon of the discount
+}
+
+// Coupon is a code given at checkout to apply a coupon only discount to a basket
+type Coupon struct {
+	Code       string    `json:"code"`
+	DiscountId uuid.UUID `json:"discount_id"`
+	MaxUses    int       `json:"max_uses"` // zero means unlimited
+	Uses       int       `json:"uses"`     // number of closed baskets the coupon used in
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// DiscountLine is the amount of a discount applied on a sale
+type DiscountLine struct {
+	DiscountId uuid.UUID       `json:"discount_id"`
+	Name       string          `json:"name"`
+	ItemId     uuid.UUID       `json:"item_id"` // nil for basket discounts
+	Amount     decimal.Decimal `json:"amount"`
+}
+
+// Matches checks item and category discounts applies to given item, basket discounts applies to all items
+func (d *Discount) Matches(item *InventoryItem) bool {
+	switch d.Scope {
+	case DiscountScopeItem, DiscountScopeBuyXGetY:
+		return uuid.Equal(d.ItemId, item.Id)
+	case DiscountScopeCategory:
+		return uuid.Equal(d.CategoryId, item.CategoryId)
+	case DiscountScopeBasket:
+		return true
+	default:
+		return false
+	}
+}
+
+// NormalizeCouponCode returns given code in the form coupons are stored, codes are not case sensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// IsExpiredAt checks coupon expired at given time, coupons without expiry time never expire
+func (c *Coupon) IsExpiredAt(at time.Time) bool {
+	return !c.ExpiresAt.IsZero() && !at.Before(c.ExpiresAt)
+}
+
+// IsUsedUp checks coupon reached its usage limit
+func (c *Coupon) IsUsedUp() bool {
+	return c.MaxUses > 0 && c.Uses >= c.MaxUses
+}
+
+func (d *Discount) String() string {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (dl *DiscountLine) String() string {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (c *Coupon) String() string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,13 @@
+package models
+
+import "fmt"
+
+// InvalidValueError is returned when a text can't be parsed as one of the known values of a type
+type InvalidValueError struct {
+	Type  string
+	Value string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid %s %q", e.Type, e.Value)
+}
@@ -22,11 +22,13 @@ type Category struct {
 
 // InventoryItem represents the physical good available
 type InventoryItem struct {
-	Id         uuid.UUID       `json:"id"`
-	Name       string          `json:"name"`
-	CategoryId uuid.UUID       `json:"category"`
-	Origin     ItemOrigin      `json:"origin"`
-	Price      decimal.Decimal `json:"price"`
+	Id           uuid.UUID       `json:"id"`
+	Name         string          `json:"name"`
+	CategoryId   uuid.UUID       `json:"category"`
+	Origin       ItemOrigin      `json:"origin"`
+	Price        decimal.Decimal `json:"price"`
+	TaxInclusive bool            `json:"tax_inclusive"` // refers to price is shelf price which already contains taxes
+	Tags         []string        `json:"tags,omitempty"`
 }
 
 func (c *Category) String() string {
@@ -45,16 +47,34 @@ func (i *InventoryItem) String() string {
 	return string(b)
 }
 
-func (io *ItemOrigin) UnmarshalText(b []byte) error {
-	str := strings.Trim(string(b), `"`)
-
-	switch str {
-	case "IMPORT", "LOCAL":
-		*io = ItemOrigin(str)
+// HasTag checks item has given tag, tags are case insensitive
+func (i *InventoryItem) HasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
 
+// IsValid checks origin is one of the known item origins
+func (io ItemOrigin) IsValid() bool {
+	switch io {
+	case ItemOriginImported, ItemOriginLocal:
+		return true
 	default:
-		*io = ItemOriginLocal
+		return false
+	}
+}
+
+func (io *ItemOrigin) UnmarshalText(b []byte) error {
+	str := ItemOrigin(strings.Trim(string(b), `"`))
+
+	// missing origin is validated by inventory service
+	if str != "" && !str.IsValid() {
+		return &InvalidValueError{Type: "item origin", Value: string(str)}
 	}
 
+	*io = str
 	return nil
 }
@@ -17,12 +17,12 @@ func TestItemOrigin_UnmarshalText(t *testing.T) {
 	assert.Equal(t, item.Origin, models.ItemOriginImported)
 }
 
-func TestItemOrigin_UnmarshalText_WhenNotKnownOption_ThenShouldAssignUnknown(t *testing.T) {
+func TestItemOrigin_UnmarshalText_WhenNotKnownOption_ThenShouldReturnErr(t *testing.T) {
 	str := "{\"origin\":\"NOT OPTION\"}"
 
 	var item models.InventoryItem
 
-	json.Unmarshal([]byte(str), &item)
+	err := json.Unmarshal([]byte(str), &item)
 
-	assert.Equal(t, item.Origin, models.ItemOriginLocal)
+	assert.Equal(t, &models.InvalidValueError{Type: "item origin", Value: "NOT OPTION"}, err)
 }
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
+	"time"
 )
 
 type BasketState string
@@ -15,32 +16,102 @@ const (
 	BasketStateCancelled BasketState = "CANCELLED"
 )
 
+// RepricingPolicy is defines when prices and taxes of basket items calculated
+type RepricingPolicy string
+
+const (
+	RepricingLockAtAdd RepricingPolicy = "LOCK_AT_ADD"      // refers to items priced when first added to basket, until basket repriced
+	RepricingAtClose   RepricingPolicy = "REPRICE_AT_CLOSE" // refers to all items priced again with current prices and taxes on close
+)
+
 // Basket represents a record of the items that customer have chosen to buy
 type Basket struct {
-	Id    uuid.UUID                 `json:"id"`
-	Items map[uuid.UUID]*BasketItem `json:"items"`
-	State BasketState               `json:"state"`
+	Id        uuid.UUID                 `json:"id"`
+	Items     map[uuid.UUID]*BasketItem `json:"items"`
+	State     BasketState               `json:"state"`
+	CreatedAt time.Time                 `json:"created_at"`
+	PricedAt  time.Time                 `json:"priced_at"` // time items last repriced at, zero means items priced at creation
+	ZoneId    uuid.UUID                 `json:"zone_id"`   // zone of the sale, nil means only taxes without zone applied
+	Version   int                       `json:"version"`   // incremented on each save, used for detecting concurrent updates
+
+	Certificate *ExemptionCertificate `json:"certificate,omitempty"` // tax exemption certificate of the customer
+	Coupons     []string              `json:"coupons,omitempty"`     // codes of coupons applied to the basket
 }
 
 type BasketItem struct {
 	*SaleItem
 
-	Count int `json:"count"`
+	Count    int       `json:"count"`
+	PricedAt time.Time `json:"priced_at"` // time price and taxes of the item calculated
 }
 
 // Receipt represents written acknowledgment that something of value has been received.
+//
+// Items keep listed prices and taxes of listed prices. When taxes are calculated after discounts, TaxBreakdown and
+// TotalTax are calculated on discounted prices, so taxes of items don't add up to TotalTax.
 type Receipt struct {
-	Id uuid.UUID `json:"id"`
+	Id       uuid.UUID `json:"id"`
+	BasketId uuid.UUID `json:"basket_id"` // basket closed with the receipt
+	ZoneId   uuid.UUID `json:"zone_id"`   // zone of the sale
+	IssuedAt time.Time `json:"issued_at"`
+
+	Items        []*BasketItem   `json:"items"`
+	TaxBreakdown []*TaxLine      `json:"tax_breakdown"`
+	TotalTax     decimal.Decimal `json:"total_tax"`
+	TotalPrice   decimal.Decimal `json:"total_price"`
+	TotalGross   decimal.Decimal `json:"total_gross"`
+
+	Certificate *ExemptionCertificate `json:"certificate,omitempty"` // tax exemption certificate applied to the sale
+
+	Discounts     []*DiscountLine   `json:"discounts,omitempty"`
+	Coupons       []string          `json:"coupons,omitempty"`
+	TotalDiscount decimal.Decimal   `json:"total_discount"`
+	TaxPolicy     DiscountTaxPolicy `json:"tax_policy,omitempty"` // refers to taxes calculated before or after discounts
+}
+
+// QuoteLine is an item to be priced in a quote, either an inventory item with id or an ad-hoc item
+type QuoteLine struct {
+	ItemId uuid.UUID      `json:"item_id"`
+	Item   *InventoryItem `json:"item,omitempty"`
+	Count  int            `json:"count"`
+}
 
-	basketID   uuid.UUID
-	Items      []*BasketItem   `json:"items"`
-	TotalTax   decimal.Decimal `json:"total_tax"`
-	TotalPrice decimal.Decimal `json:"total_price"`
-	TotalGross decimal.Decimal `json:"total_gross"`
+// Quote represents prices and taxes of items calculated without a basket
+type Quote struct {
+	ZoneId       uuid.UUID       `json:"zone_id"`
+	At           time.Time       `json:"at"`
+	Items        []*BasketItem   `json:"items"`
+	TaxBreakdown []*TaxLine      `json:"tax_breakdown"`
+	TotalTax     decimal.Decimal `json:"total_tax"`
+	TotalPrice   decimal.Decimal `json:"total_price"`
+	TotalGross   decimal.Decimal `json:"total_gross"`
+}
+
+// HasCoupon checks coupon with given normalized code applied to the basket
+func (b *Basket) HasCoupon(code string) bool {
+	for _, c := range b.Coupons {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// TaxScope returns scope for selecting taxes of basket items, taxes in force when basket last repriced or created.
+// Baskets saved without creation time use current time.
+func (b *Basket) TaxScope() TaxScope {
+	at := b.PricedAt
+	if at.IsZero() {
+		at = b.CreatedAt
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return TaxScope{At: at, ZoneId: b.ZoneId, Certificate: b.Certificate}
 }
 
 func (bi *BasketItem) TotalPrice() decimal.Decimal {
-	return bi.Price.Mul(decimal.NewFromFloat32(float32(bi.Count)))
+	return bi.Net().Mul(decimal.NewFromFloat32(float32(bi.Count)))
 }
 
 func (bi *BasketItem) TotalTax() decimal.Decimal {
@@ -51,6 +122,25 @@ func (bi *BasketItem) TotalGross() decimal.Decimal {
 	return bi.Gross.Mul(decimal.NewFromFloat32(float32(bi.Count)))
 }
 
+// TotalBreakdown returns tax breakdown of the sale item multiplied with item count
+func (bi *BasketItem) TotalBreakdown() []*TaxLine {
+	lines := make([]*TaxLine, 0, len(bi.Breakdown))
+	for _, v := range bi.Breakdown {
+		lines = append(lines, &TaxLine{
+			TaxId:    v.TaxId,
+			Name:     v.Name,
+			Kind:     v.Kind,
+			Rate:     v.Rate,
+			Rounding: v.Rounding,
+			Priority: v.Priority,
+			Compound: v.Compound,
+			Base:     v.Base.Mul(decimal.NewFromFloat32(float32(bi.Count))),
+			Amount:   v.Amount.Mul(decimal.NewFromFloat32(float32(bi.Count))),
+		})
+	}
+	return lines
+}
+
 func (bi *BasketItem) Print() string {
 	return fmt.Sprintf("%v %s: %s", bi.Count, bi.Name, bi.TotalGross())
 }
@@ -58,9 +148,16 @@ func (bi *BasketItem) Print() string {
 func (r *Receipt) Print() {
 	fmt.Println("=====================================================")
 	fmt.Printf("Receipt %s : \n", r.Id)
+	fmt.Printf("Basket %s, issued at %s \n", r.BasketId, r.IssuedAt.Format(time.RFC3339))
 	for _, v := range r.Items {
 		fmt.Println(v.Print())
 	}
+	for _, v := range r.Discounts {
+		fmt.Printf("%s: -%s \n", v.Name, v.Amount)
+	}
+	for _, v := range r.TaxBreakdown {
+		fmt.Printf("%s: %s \n", v.Name, v.Amount)
+	}
 	fmt.Printf("Sales Taxes: %s \n", r.TotalTax)
 	fmt.Printf("Total: %s \n", r.TotalGross)
 	fmt.Println("=====================================================")
@@ -89,3 +186,11 @@ func (r *Receipt) String() string {
 	}
 	return string(b)
 }
+
+func (ql *QuoteLine) String() string {
+	b, err := json.Marshal(ql)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,28 @@
+package models_test
+
+import (
+	"github.com/aweris/stp/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBasket_TaxScope(t *testing.T) {
+	createdAt := time.Date(2019, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	b := &models.Basket{CreatedAt: createdAt}
+	assert.Equal(t, createdAt, b.TaxScope().At)
+
+	b.PricedAt = createdAt.Add(time.Hour)
+	assert.Equal(t, createdAt.Add(time.Hour), b.TaxScope().At)
+}
+
+func TestBasket_TaxScope_WhenCreationTimeMissing_ThenShouldUseCurrentTime(t *testing.T) {
+	before := time.Now()
+
+	b := &models.Basket{}
+
+	at := b.TaxScope().At
+	assert.False(t, at.Before(before))
+	assert.False(t, at.After(time.Now()))
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 	"strings"
+	"time"
 )
 
 // TaxOrigin is defines tax scope according to inventory item origin
@@ -20,26 +21,347 @@ const (
 type TaxCondition string
 
 const (
-	UnknownTC    TaxCondition = "UNKNOWN"
-	ExemptToTax  TaxCondition = "EXEMPT"  // refers to only tax types in context will be free from tax
-	SubjectToTax TaxCondition = "SUBJECT" // refers to only tax types in context will be effected from tax
+	AllCategories TaxCondition = "ALL"     // refers to all categories will be effected from tax, categories in context must be empty
+	ExemptToTax   TaxCondition = "EXEMPT"  // refers to only tax types in context will be free from tax
+	SubjectToTax  TaxCondition = "SUBJECT" // refers to only tax types in context will be effected from tax
 )
 
+// TaxKind is defines how amount of a tax calculated
+type TaxKind string
+
+const (
+	TaxKindPercentage TaxKind = "PERCENTAGE" // refers to tax calculated as rate percent of price
+	TaxKindFixed      TaxKind = "FIXED"      // refers to tax adding a fixed amount per unit, like deposits or eco-fees
+)
+
+// TaxCalculationMode is defines how applicable taxes of a sale item calculated
+type TaxCalculationMode string
+
+const (
+	TaxCalculationCombined TaxCalculationMode = "COMBINED" // refers to summing rates of applicable taxes and rounding once
+	TaxCalculationPerTax   TaxCalculationMode = "PER_TAX"  // refers to calculating and rounding each applicable tax individually
+)
+
+// RoundingMode is defines direction of rounding for calculated tax amounts
+type RoundingMode string
+
+const (
+	RoundingModeCeil     RoundingMode = "CEIL"      // rounds up to the next increment
+	RoundingModeFloor    RoundingMode = "FLOOR"     // rounds down to the previous increment
+	RoundingModeHalfUp   RoundingMode = "HALF_UP"   // rounds to the nearest increment, ties away from zero
+	RoundingModeHalfEven RoundingMode = "HALF_EVEN" // rounds to the nearest increment, ties to even (banker's rounding)
+)
+
+// DefaultRoundingRule returns the rule used for taxes without rounding rule. It rounds tax amounts up to the nearest 0.05
+func DefaultRoundingRule() *RoundingRule {
+	return &RoundingRule{Increment: decimal.New(5, -2), Mode: RoundingModeCeil}
+}
+
+// RoundingRule defines how a calculated tax amount is rounded
+type RoundingRule struct {
+	Increment decimal.Decimal `json:"increment"`
+	Mode      RoundingMode    `json:"mode"`
+}
+
+// TaxRuleType is defines how a tax rule evaluated against an inventory item
+type TaxRuleType string
+
+const (
+	TaxRuleAnd        TaxRuleType = "AND"         // refers to all sub rules must match
+	TaxRuleOr         TaxRuleType = "OR"          // refers to at least one of sub rules must match
+	TaxRuleNot        TaxRuleType = "NOT"         // refers to the only sub rule must not match
+	TaxRulePriceAbove TaxRuleType = "PRICE_ABOVE" // refers to item price must be greater than amount
+	TaxRulePriceBelow TaxRuleType = "PRICE_BELOW" // refers to item price must be less than amount
+	TaxRuleItems      TaxRuleType = "ITEMS"       // refers to item must be one of the items
+	TaxRuleTags       TaxRuleType = "TAGS"        // refers to item must have one of the tags
+)
+
+// TaxRule is a predicate tree narrowing down items a tax applies to, in addition to tax origin and condition
+type TaxRule struct {
+	Type   TaxRuleType      `json:"type"`
+	Rules  []*TaxRule       `json:"rules,omitempty"`
+	Amount *decimal.Decimal `json:"amount,omitempty"`
+	Items  []uuid.UUID      `json:"items,omitempty"`
+	Tags   []string         `json:"tags,omitempty"`
+}
+
+// Zone is a jurisdiction where sales happen, taxes can be limited to zones
+type Zone struct {
+	Id   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// ExemptionCertificate exempts holder of the certificate from listed taxes until it expires
+type ExemptionCertificate struct {
+	Id        string      `json:"id"` // number given to certificate by issuing authority
+	Holder    string      `json:"holder"`
+	TaxIds    []uuid.UUID `json:"tax_ids"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// TaxScope is the place and time taxes of a sale selected for
+type TaxScope struct {
+	At          time.Time             `json:"at"`
+	ZoneId      uuid.UUID             `json:"zone_id"`
+	Certificate *ExemptionCertificate `json:"certificate,omitempty"` // taxes covered by certificate skipped
+}
+
 // Tax
 type Tax struct {
 	Id         uuid.UUID          `json:"id"`
 	Name       string             `json:"name"`
+	Kind       TaxKind            `json:"kind,omitempty"` // empty kind means percentage tax
 	Rate       decimal.Decimal    `json:"rate"`
+	Amount     decimal.Decimal    `json:"amount"` // amount per unit for fixed taxes
 	Origin     TaxOrigin          `json:"origin"`
 	Condition  TaxCondition       `json:"condition"`
 	Categories map[uuid.UUID]bool `json:"categories"`
+	Rounding   *RoundingRule      `json:"rounding,omitempty"`
+	Rule       *TaxRule           `json:"rule,omitempty"`       // nil means tax applies to all items matching origin and condition
+	Priority   int                `json:"priority"`             // taxes applied in ascending priority order, same priority taxes applied together
+	Compound   bool               `json:"compound"`             // refers to tax levied on price plus taxes with lower priority
+	Zones      []uuid.UUID        `json:"zones,omitempty"`      // empty means tax applies in all zones
+	ValidFrom  *time.Time         `json:"valid_from,omitempty"` // inclusive start of validity, nil means tax valid since beginning
+	ValidTo    *time.Time         `json:"valid_to,omitempty"`   // exclusive end of validity, nil means tax valid until further notice
+}
+
+// TaxAction is defines type of a change made on a tax
+type TaxAction string
+
+const (
+	TaxActionCreated TaxAction = "CREATED"
+	TaxActionUpdated TaxAction = "UPDATED"
+	TaxActionDeleted TaxAction = "DELETED"
+)
+
+// TaxVersion is an immutable record of a change made on a tax
+type TaxVersion struct {
+	TaxId     uuid.UUID `json:"tax_id"`
+	Version   uint64    `json:"version"`
+	Action    TaxAction `json:"action"`
+	ChangedBy string    `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+	Old       *Tax      `json:"old,omitempty"` // state of the tax before change, nil for created taxes
+	New       *Tax      `json:"new,omitempty"` // state of the tax after change, nil for deleted taxes
+}
+
+// TaxConflictReason is defines why two taxes considered as conflicting
+type TaxConflictReason string
+
+const (
+	TaxConflictSameName     TaxConflictReason = "SAME_NAME"     // refers to overlapping taxes with same name
+	TaxConflictSameCoverage TaxConflictReason = "SAME_COVERAGE" // refers to overlapping taxes with same origin, condition and categories
+	TaxConflictOverlap      TaxConflictReason = "OVERLAP"       // refers to taxes charged together on some items, in some zones at some time
+)
+
+// TaxConflict represents two taxes charged together for a sale, one of them likely a duplicate unless the reason is
+// an overlap
+type TaxConflict struct {
+	Tax         *Tax              `json:"tax"`
+	Conflicting *Tax              `json:"conflicting"`
+	Reason      TaxConflictReason `json:"reason"`
+}
+
+// TaxLine represents amount of a single tax applied to a sale item with a snapshot of the tax definition used in
+// calculation, so it stays reproducible even after tax changed or deleted.
+type TaxLine struct {
+	TaxId    uuid.UUID       `json:"tax_id"`
+	Name     string          `json:"name"`
+	Kind     TaxKind         `json:"kind,omitempty"`
+	Rate     decimal.Decimal `json:"rate"`
+	Rounding *RoundingRule   `json:"rounding"`
+	Priority int             `json:"priority"`
+	Compound bool            `json:"compound"`
+	Base     decimal.Decimal `json:"base"` // amount the tax levied on
+	Amount   decimal.Decimal `json:"amount"`
 }
 
 type SaleItem struct {
 	*InventoryItem
 
-	Taxes decimal.Decimal `json:"taxes"`
-	Gross decimal.Decimal `json:"gross"`
+	Taxes     decimal.Decimal `json:"taxes"`
+	Gross     decimal.Decimal `json:"gross"`
+	Breakdown []*TaxLine      `json:"breakdown"`
+}
+
+// IsValid checks rule has a positive increment and a known rounding mode
+func (rr *RoundingRule) IsValid() bool {
+	if !rr.Increment.IsPositive() {
+		return false
+	}
+
+	switch rr.Mode {
+	case RoundingModeCeil, RoundingModeFloor, RoundingModeHalfUp, RoundingModeHalfEven:
+		return true
+	default:
+		return false
+	}
+}
+
+// Round rounds given amount to a multiple of rule increment according to rule mode
+func (rr *RoundingRule) Round(amount decimal.Decimal) decimal.Decimal {
+	steps := amount.Div(rr.Increment)
+
+	switch rr.Mode {
+	case RoundingModeFloor:
+		steps = steps.Floor()
+	case RoundingModeHalfUp:
+		steps = steps.Round(0)
+	case RoundingModeHalfEven:
+		steps = steps.RoundBank(0)
+	default:
+		steps = steps.Ceil()
+	}
+
+	return steps.Mul(rr.Increment)
+}
+
+// Equal checks rules round the same way, increments compared by value so 0.05 and 0.050 are equal
+func (rr *RoundingRule) Equal(other *RoundingRule) bool {
+	return rr.Mode == other.Mode && rr.Increment.Equal(other.Increment)
+}
+
+func (rr *RoundingRule) String() string {
+	b, err := json.Marshal(rr)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// IsValid checks rule and all of its sub rules have parameters required by their types
+func (tr *TaxRule) IsValid() bool {
+	switch tr.Type {
+	case TaxRuleAnd, TaxRuleOr:
+		if len(tr.Rules) == 0 {
+			return false
+		}
+	case TaxRuleNot:
+		if len(tr.Rules) != 1 {
+			return false
+		}
+	case TaxRulePriceAbove, TaxRulePriceBelow:
+		return tr.Amount != nil && !tr.Amount.IsNegative()
+	case TaxRuleItems:
+		return len(tr.Items) > 0
+	case TaxRuleTags:
+		return len(tr.Tags) > 0
+	default:
+		return false
+	}
+
+	for _, r := range tr.Rules {
+		if r == nil || !r.IsValid() {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches evaluates rule against given item
+func (tr *TaxRule) Matches(item *InventoryItem) bool {
+	switch tr.Type {
+	case TaxRuleAnd:
+		for _, r := range tr.Rules {
+			if !r.Matches(item) {
+				return false
+			}
+		}
+		return true
+	case TaxRuleOr:
+		for _, r := range tr.Rules {
+			if r.Matches(item) {
+				return true
+			}
+		}
+		return false
+	case TaxRuleNot:
+		return !tr.Rules[0].Matches(item)
+	case TaxRulePriceAbove:
+		return item.Price.GreaterThan(*tr.Amount)
+	case TaxRulePriceBelow:
+		return item.Price.LessThan(*tr.Amount)
+	case TaxRuleItems:
+		for _, id := range tr.Items {
+			if uuid.Equal(id, item.Id) {
+				return true
+			}
+		}
+		return false
+	case TaxRuleTags:
+		for _, tag := range tr.Tags {
+			if item.HasTag(tag) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// IsFixed checks tax adds a fixed amount per unit instead of a percentage of price
+func (t *Tax) IsFixed() bool {
+	return t.Kind == TaxKindFixed
+}
+
+// EffectiveRounding returns a copy of rounding rule of the tax or default rounding rule if tax doesn't have one, so
+// tax lines keeping the rule don't share it with the tax
+func (t *Tax) EffectiveRounding() *RoundingRule {
+	if t.Rounding == nil {
+		return DefaultRoundingRule()
+	}
+	rule := *t.Rounding
+	return &rule
+}
+
+// IsValidAt checks tax is in force at given time
+func (t *Tax) IsValidAt(at time.Time) bool {
+	if t.ValidFrom != nil && at.Before(*t.ValidFrom) {
+		return false
+	}
+	if t.ValidTo != nil && !at.Before(*t.ValidTo) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo checks tax origin and category condition matches with given item origin and category
+func (t *Tax) AppliesTo(origin ItemOrigin, categoryId uuid.UUID) bool {
+	if string(t.Origin) != string(origin) && t.Origin != TaxOriginAll {
+		return false
+	}
+
+	exist := t.Categories[categoryId]
+
+	switch t.Condition {
+	case SubjectToTax:
+		return exist
+	case ExemptToTax:
+		return !exist
+	default:
+		// all categories, also taxes saved without condition before it was required
+		return true
+	}
+}
+
+// AppliesInZone checks tax is levied in given zone
+func (t *Tax) AppliesInZone(zoneId uuid.UUID) bool {
+	if len(t.Zones) == 0 {
+		return true
+	}
+
+	for _, id := range t.Zones {
+		if uuid.Equal(id, zoneId) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesRule checks given item satisfies the rule of the tax, taxes without rule matches all items
+func (t *Tax) MatchesRule(item *InventoryItem) bool {
+	return t.Rule == nil || t.Rule.Matches(item)
 }
 
 func (t *Tax) String() string {
@@ -50,6 +372,62 @@ func (t *Tax) String() string {
 	return string(b)
 }
 
+// Covers checks certificate exempts from given tax at given time
+func (ec *ExemptionCertificate) Covers(taxId uuid.UUID, at time.Time) bool {
+	if !at.Before(ec.ExpiresAt) {
+		return false
+	}
+
+	for _, id := range ec.TaxIds {
+		if uuid.Equal(id, taxId) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExempt checks tax is covered by exemption certificate of the scope
+func (ts TaxScope) IsExempt(taxId uuid.UUID) bool {
+	return ts.Certificate != nil && ts.Certificate.Covers(taxId, ts.At)
+}
+
+func (ec *ExemptionCertificate) String() string {
+	b, err := json.Marshal(ec)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (z *Zone) String() string {
+	b, err := json.Marshal(z)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Net returns price of the sale item without taxes
+func (si *SaleItem) Net() decimal.Decimal {
+	return si.Gross.Sub(si.Taxes)
+}
+
+func (tv *TaxVersion) String() string {
+	b, err := json.Marshal(tv)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (tl *TaxLine) String() string {
+	b, err := json.Marshal(tl)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
 func (si *SaleItem) String() string {
 	b, err := json.Marshal(si)
 	if err != nil {
@@ -58,30 +436,46 @@ func (si *SaleItem) String() string {
 	return string(b)
 }
 
-func (tt *TaxOrigin) UnmarshalText(b []byte) error {
-	str := strings.Trim(string(b), `"`)
+// IsValid checks origin is one of the known tax origins
+func (tt TaxOrigin) IsValid() bool {
+	switch tt {
+	case TaxOriginAll, TaxOriginLocal, TaxOriginImport:
+		return true
+	default:
+		return false
+	}
+}
 
-	switch str {
-	case "LOCAL", "IMPORT", "ALL":
-		*tt = TaxOrigin(str)
+func (tt *TaxOrigin) UnmarshalText(b []byte) error {
+	str := TaxOrigin(strings.Trim(string(b), `"`))
 
-	default:
-		*tt = TaxOriginAll
+	// missing origin is validated by tax service
+	if str != "" && !str.IsValid() {
+		return &InvalidValueError{Type: "tax origin", Value: string(str)}
 	}
 
+	*tt = str
 	return nil
 }
 
-func (tc *TaxCondition) UnmarshalText(b []byte) error {
-	str := strings.Trim(string(b), `"`)
+// IsValid checks condition is one of the known tax conditions
+func (tc TaxCondition) IsValid() bool {
+	switch tc {
+	case AllCategories, ExemptToTax, SubjectToTax:
+		return true
+	default:
+		return false
+	}
+}
 
-	switch str {
-	case "EXEMPT", "SUBJECT":
-		*tc = TaxCondition(str)
+func (tc *TaxCondition) UnmarshalText(b []byte) error {
+	str := TaxCondition(strings.Trim(string(b), `"`))
 
-	default:
-		*tc = UnknownTC
+	// missing condition is resolved by tax service from categories
+	if str != "" && !str.IsValid() {
+		return &InvalidValueError{Type: "tax condition", Value: string(str)}
 	}
 
+	*tc = str
 	return nil
 }
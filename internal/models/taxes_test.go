@@ -3,8 +3,11 @@ package models_test
 import (
 	"encoding/json"
 	"github.com/aweris/stp/internal/models"
+	"github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestTaxCondition_UnmarshalText(t *testing.T) {
@@ -17,14 +20,24 @@ func TestTaxCondition_UnmarshalText(t *testing.T) {
 	assert.Equal(t, tax.Condition, models.ExemptToTax)
 }
 
-func TestTaxCondition_UnmarshalText_WhenNotKnownOption_ThenShouldAssignUnknown(t *testing.T) {
+func TestTaxCondition_UnmarshalText_WhenNotKnownOption_ThenShouldReturnErr(t *testing.T) {
 	str := "{\"condition\":\"NOT OPTION\"}"
 
 	var tax models.Tax
 
-	json.Unmarshal([]byte(str), &tax)
+	err := json.Unmarshal([]byte(str), &tax)
+
+	assert.Equal(t, &models.InvalidValueError{Type: "tax condition", Value: "NOT OPTION"}, err)
+}
+
+func TestTaxCondition_UnmarshalText_WhenUnknown_ThenShouldReturnErr(t *testing.T) {
+	str := "{\"condition\":\"UNKNOWN\"}"
 
-	assert.Equal(t, tax.Condition, models.UnknownTC)
+	var tax models.Tax
+
+	err := json.Unmarshal([]byte(str), &tax)
+
+	assert.Error(t, err)
 }
 
 func TestTaxOrigin_UnmarshalText(t *testing.T) {
@@ -37,12 +50,143 @@ func TestTaxOrigin_UnmarshalText(t *testing.T) {
 	assert.Equal(t, tax.Origin, models.TaxOriginLocal)
 }
 
-func TestTaxType_UnmarshalText_WhenNotKnownOption_ThenShouldAssignUnknown(t *testing.T) {
+func TestTaxType_UnmarshalText_WhenNotKnownOption_ThenShouldReturnErr(t *testing.T) {
 	str := "{\"origin\":\"NOT OPTION\"}"
 
 	var tax models.Tax
 
-	json.Unmarshal([]byte(str), &tax)
+	err := json.Unmarshal([]byte(str), &tax)
+
+	assert.Equal(t, &models.InvalidValueError{Type: "tax origin", Value: "NOT OPTION"}, err)
+}
+
+func TestTax_UnmarshalText_WhenOriginAndConditionEmpty_ThenShouldKeepZeroValues(t *testing.T) {
+	str := "{\"origin\":\"\",\"condition\":\"\"}"
+
+	var tax models.Tax
+
+	err := json.Unmarshal([]byte(str), &tax)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.TaxOrigin(""), tax.Origin)
+	assert.Equal(t, models.TaxCondition(""), tax.Condition)
+}
+
+func TestRoundingRule_Round(t *testing.T) {
+	cent := decimal.NewFromFloat32(0.01)
+	amount := decimal.NewFromFloat(1.125)
+
+	cases := map[models.RoundingMode]decimal.Decimal{
+		models.RoundingModeCeil:     decimal.NewFromFloat(1.13),
+		models.RoundingModeFloor:    decimal.NewFromFloat(1.12),
+		models.RoundingModeHalfUp:   decimal.NewFromFloat(1.13),
+		models.RoundingModeHalfEven: decimal.NewFromFloat(1.12),
+	}
+
+	for mode, expected := range cases {
+		rule := &models.RoundingRule{Increment: cent, Mode: mode}
+
+		assert.True(t, rule.Round(amount).Equal(expected), "unexpected result for %s", mode)
+	}
+}
+
+func TestRoundingRule_Round_WithDefaultRule_ThenShouldRoundUpToNearestFiveCents(t *testing.T) {
+	rounded := models.DefaultRoundingRule().Round(decimal.NewFromFloat(1.499))
+
+	assert.True(t, rounded.Equal(decimal.NewFromFloat(1.5)))
+}
+
+func TestRoundingRule_Equal_WhenIncrementsHaveDifferentScale_ThenShouldReturnTrue(t *testing.T) {
+	rule := &models.RoundingRule{Increment: decimal.New(5, -2), Mode: models.RoundingModeCeil}
+	other := &models.RoundingRule{Increment: decimal.New(50, -3), Mode: models.RoundingModeCeil}
+
+	assert.True(t, rule.Equal(other))
+
+	other.Mode = models.RoundingModeFloor
+	assert.False(t, rule.Equal(other))
+}
+
+func TestTax_EffectiveRounding_WhenRuleModified_ThenShouldNotChangeTaxOrDefaultRule(t *testing.T) {
+	tax := &models.Tax{Rounding: &models.RoundingRule{Increment: decimal.New(1, -2), Mode: models.RoundingModeHalfUp}}
+
+	rule := tax.EffectiveRounding()
+	rule.Mode = models.RoundingModeFloor
+	assert.Equal(t, models.RoundingModeHalfUp, tax.Rounding.Mode)
+
+	tax.Rounding = nil
+
+	rule = tax.EffectiveRounding()
+	rule.Increment = decimal.New(1, 0)
+	assert.True(t, models.DefaultRoundingRule().Increment.Equal(decimal.New(5, -2)))
+	assert.True(t, tax.EffectiveRounding().Increment.Equal(decimal.New(5, -2)))
+}
+
+func TestRoundingRule_IsValid_WhenIncrementIsNotPositive_ThenShouldReturnFalse(t *testing.T) {
+	rule := &models.RoundingRule{Increment: decimal.Zero, Mode: models.RoundingModeCeil}
+
+	assert.False(t, rule.IsValid())
+}
+
+func TestRoundingRule_IsValid_WhenModeIsUnknown_ThenShouldReturnFalse(t *testing.T) {
+	rule := &models.RoundingRule{Increment: decimal.NewFromFloat32(0.01), Mode: "NOT OPTION"}
+
+	assert.False(t, rule.IsValid())
+}
+
+func TestTax_IsValidAt(t *testing.T) {
+	from := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tax := &models.Tax{ValidFrom: &from, ValidTo: &to}
+
+	assert.False(t, tax.IsValidAt(from.Add(-time.Second)))
+	assert.True(t, tax.IsValidAt(from))
+	assert.True(t, tax.IsValidAt(to.Add(-time.Second)))
+	assert.False(t, tax.IsValidAt(to))
+}
+
+func TestTax_IsValidAt_WhenValidityNotDefined_ThenShouldAlwaysBeValid(t *testing.T) {
+	tax := &models.Tax{}
+
+	assert.True(t, tax.IsValidAt(time.Time{}))
+	assert.True(t, tax.IsValidAt(time.Now()))
+}
+
+func TestTax_AppliesTo(t *testing.T) {
+	category := uuid.NewV1()
+
+	subject := &models.Tax{Origin: models.TaxOriginImport, Condition: models.SubjectToTax, Categories: map[uuid.UUID]bool{category: true}}
+	exempt := &models.Tax{Origin: models.TaxOriginAll, Condition: models.ExemptToTax, Categories: map[uuid.UUID]bool{category: true}}
+
+	assert.True(t, subject.AppliesTo(models.ItemOriginImported, category))
+	assert.False(t, subject.AppliesTo(models.ItemOriginLocal, category))
+	assert.False(t, subject.AppliesTo(models.ItemOriginImported, uuid.NewV1()))
+
+	assert.False(t, exempt.AppliesTo(models.ItemOriginLocal, category))
+	assert.True(t, exempt.AppliesTo(models.ItemOriginLocal, uuid.NewV1()))
+}
+
+func TestTaxRule_IsValid(t *testing.T) {
+	amount := decimal.NewFromFloat32(1000)
+
+	assert.True(t, (&models.TaxRule{Type: models.TaxRulePriceAbove, Amount: &amount}).IsValid())
+	assert.True(t, (&models.TaxRule{Type: models.TaxRuleNot, Rules: []*models.TaxRule{{Type: models.TaxRuleTags, Tags: []string{"food"}}}}).IsValid())
+
+	assert.False(t, (&models.TaxRule{Type: "NOT OPTION"}).IsValid())
+	assert.False(t, (&models.TaxRule{Type: models.TaxRulePriceBelow}).IsValid())
+	assert.False(t, (&models.TaxRule{Type: models.TaxRuleItems}).IsValid())
+	assert.False(t, (&models.TaxRule{Type: models.TaxRuleOr}).IsValid())
+	assert.False(t, (&models.TaxRule{Type: models.TaxRuleAnd, Rules: []*models.TaxRule{{Type: models.TaxRuleTags}}}).IsValid())
+}
+
+func TestTaxRule_UnmarshalJSON(t *testing.T) {
+	var rule models.TaxRule
+
+	err := json.Unmarshal([]byte(`{"type":"OR","rules":[{"type":"PRICE_ABOVE","amount":"1000"},{"type":"TAGS","tags":["luxury"]}]}`), &rule)
+	assert.NoError(t, err)
+	assert.True(t, rule.IsValid())
 
-	assert.Equal(t, tax.Origin, models.TaxOriginAll)
+	assert.True(t, rule.Matches(&models.InventoryItem{Price: decimal.NewFromFloat32(1000.01)}))
+	assert.True(t, rule.Matches(&models.InventoryItem{Price: decimal.NewFromFloat32(10), Tags: []string{"luxury"}}))
+	assert.False(t, rule.Matches(&models.InventoryItem{Price: decimal.NewFromFloat32(1000)}))
 }
@@ -9,6 +9,29 @@ var (
 	ErrInvalidItemCount = errors.New("invalid item count")
 	ErrBasketNotOpen    = errors.New("basket not open")
 	ErrNotItemInBasket  = errors.New("there is no item in basket")
+	ErrBasketConflict   = errors.New("basket modified by another request")
+	ErrBasketNotClosed  = errors.New("basket not closed")
 
-	ErrInvalidReceiptId  = errors.New("invalid receipt id")
+	ErrInvalidReceiptId    = errors.New("invalid receipt id")
+	ErrInvalidReceiptRange = errors.New("invalid receipt date range")
+
+	ErrInvalidCertificate = errors.New("invalid exemption certificate")
+	ErrCertificateExpired = errors.New("exemption certificate expired")
+
+	ErrInvalidDiscountId       = errors.New("invalid discount id")
+	ErrInvalidDiscountName     = errors.New("invalid discount name")
+	ErrInvalidDiscountKind     = errors.New("invalid discount kind")
+	ErrInvalidDiscountRate     = errors.New("invalid discount rate")
+	ErrInvalidDiscountAmount   = errors.New("invalid discount amount")
+	ErrInvalidDiscountScope    = errors.New("invalid discount scope")
+	ErrInvalidDiscountQuantity = errors.New("invalid discount buy or get quantity")
+
+	// coupon errors are shown to cashiers at checkout
+	ErrInvalidCouponCode     = errors.New("coupon code is missing or already exists")
+	ErrInvalidCouponDiscount = errors.New("coupon must refer to an existing coupon only discount")
+	ErrInvalidCouponLimit    = errors.New("coupon usage limit can not be negative")
+	ErrCouponNotFound        = errors.New("coupon code not recognised")
+	ErrCouponExpired         = errors.New("coupon has expired")
+	ErrCouponUsedUp          = errors.New("coupon has reached its usage limit")
+	ErrCouponAlreadyApplied  = errors.New("coupon already applied to this basket")
 )
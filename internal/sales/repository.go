@@ -4,8 +4,14 @@ import (
 	"context"
 	"github.com/aweris/stp/internal/models"
 	"github.com/satori/go.uuid"
+	"time"
 )
 
+// Transactor runs changes of repositories in a single transaction, repositories join it through the given context
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type BasketRepository interface {
 	SaveBasket(ctx context.Context, basket *models.Basket) (*models.Basket, error)
 	GetBasketByID(ctx context.Context, basketId uuid.UUID) (*models.Basket, error)
@@ -15,5 +21,21 @@ type BasketRepository interface {
 type ReceiptRepository interface {
 	SaveReceipt(ctx context.Context, receipt *models.Receipt) (*models.Receipt, error)
 	GetReceiptByID(ctx context.Context, receiptId uuid.UUID) (*models.Receipt, error)
+	GetReceiptByBasketID(ctx context.Context, basketId uuid.UUID) (*models.Receipt, error)
 	FetchAllReceipts(ctx context.Context) ([]*models.Receipt, error)
+	FetchReceiptsIssuedBetween(ctx context.Context, from, to time.Time) ([]*models.Receipt, error)
+}
+
+type DiscountRepository interface {
+	SaveDiscount(ctx context.Context, discount *models.Discount) (*models.Discount, error)
+	GetDiscountByID(ctx context.Context, discountId uuid.UUID) (*models.Discount, error)
+	FetchAllDiscounts(ctx context.Context) ([]*models.Discount, error)
+	DeleteDiscount(ctx context.Context, discountId uuid.UUID) (*models.Discount, error)
+}
+
+type CouponRepository interface {
+	SaveCoupon(ctx context.Context, coupon *models.Coupon) (*models.Coupon, error)
+	GetCouponByCode(ctx context.Context, code string) (*models.Coupon, error)
+	FetchAllCoupons(ctx context.Context) ([]*models.Coupon, error)
+	DeleteCoupon(ctx context.Context, code string) (*models.Coupon, error)
 }
@@ -39,23 +39,45 @@ func NewBoltDBBasketRepository(db *storage.BoltDB) sales.BasketRepository {
 	return br
 }
 
+// SaveBasket saves the basket only if stored basket has the same version, so updates made on a stale copy are rejected
+// with sales.ErrBasketConflict. Version of given basket incremented after it is saved.
 func (br *boltDBBasketRepository) SaveBasket(ctx context.Context, basket *models.Basket) (*models.Basket, error) {
-	err := br.db.Update(func(tx *bolt.Tx) error {
+	err := br.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketBasket))
 
-		data, err := json.Marshal(basket)
+		var stored models.Basket
+		if v := tb.Get(basket.Id.Bytes()); v != nil {
+			err := json.Unmarshal(v, &stored)
+			if err != nil {
+				return err
+			}
+		}
+
+		if stored.Version != basket.Version {
+			return sales.ErrBasketConflict
+		}
+
+		next := *basket
+		next.Version++
+
+		data, err := json.Marshal(&next)
 		if err != nil {
 			return err
 		}
 
 		return tb.Put(basket.Id.Bytes(), data)
 	})
-	return basket, err
+	if err != nil {
+		return nil, err
+	}
+
+	basket.Version++
+	return basket, nil
 }
 
 func (br *boltDBBasketRepository) GetBasketByID(ctx context.Context, basketId uuid.UUID) (*models.Basket, error) {
 	var b *models.Basket
-	err := br.db.View(func(tx *bolt.Tx) error {
+	err := br.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketBasket))
 
 		v := tb.Get(basketId.Bytes())
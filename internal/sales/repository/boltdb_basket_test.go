@@ -3,6 +3,7 @@ package repository_test
 import (
 	"context"
 	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/sales"
 	basketRepository "github.com/aweris/stp/internal/sales/repository"
 	"github.com/aweris/stp/storage"
 	"github.com/satori/go.uuid"
@@ -88,3 +89,75 @@ func TestBoltDBBasketRepository_FetchAllBaskets(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(list))
 }
+
+func TestBoltDBBasketRepository_SaveBasket_WhenBasketIsStale_ThanShouldReturnConflict(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := basketRepository.NewBoltDBBasketRepository(db.BoltDB)
+
+	b := &models.Basket{
+		Id:    uuid.NewV1(),
+		State: models.BasketStateOpened,
+	}
+
+	_, err := r.SaveBasket(context.Background(), b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, b.Version)
+
+	first, err := r.GetBasketByID(context.Background(), b.Id)
+	assert.NoError(t, err)
+
+	second, err := r.GetBasketByID(context.Background(), b.Id)
+	assert.NoError(t, err)
+
+	_, err = r.SaveBasket(context.Background(), first)
+	assert.NoError(t, err)
+
+	_, err = r.SaveBasket(context.Background(), second)
+	assert.Equal(t, sales.ErrBasketConflict, err)
+
+	find, err := r.GetBasketByID(context.Background(), b.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, find.Version)
+}
+
+func TestBoltDB_RunInTransaction_WhenFails_ThanShouldRollbackBasketAndReceipt(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	br := basketRepository.NewBoltDBBasketRepository(db.BoltDB)
+	rr := basketRepository.NewBoltDBReceiptRepository(db.BoltDB)
+
+	b := &models.Basket{
+		Id:    uuid.NewV1(),
+		State: models.BasketStateOpened,
+	}
+
+	_, err := br.SaveBasket(context.Background(), b)
+	assert.NoError(t, err)
+
+	receipt := &models.Receipt{Id: uuid.NewV1()}
+
+	err = db.RunInTransaction(context.Background(), func(ctx context.Context) error {
+		_, err := rr.SaveReceipt(ctx, receipt)
+		assert.NoError(t, err)
+
+		// stale copy of the basket fails the transaction after receipt saved
+		stale := *b
+		stale.Version = 0
+		stale.State = models.BasketStateClosed
+
+		_, err = br.SaveBasket(ctx, &stale)
+		return err
+	})
+	assert.Equal(t, sales.ErrBasketConflict, err)
+
+	find, err := rr.GetReceiptByID(context.Background(), receipt.Id)
+	assert.NoError(t, err)
+	assert.Nil(t, find)
+
+	basket, err := br.GetBasketByID(context.Background(), b.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.BasketStateOpened, basket.State)
+}
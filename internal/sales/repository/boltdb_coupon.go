@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/sales"
+	"github.com/aweris/stp/storage"
+	"go.etcd.io/bbolt"
+	"log"
+)
+
+const (
+	bucketCoupon = "sales_coupon"
+)
+
+type boltDBCouponRepository struct {
+	db *storage.BoltDB
+}
+
+func (cr *boltDBCouponRepository) init() error {
+	return cr.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketCoupon))
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// NewBoltDBCouponRepository creates coupon repository for bolt db
+func NewBoltDBCouponRepository(db *storage.BoltDB) sales.CouponRepository {
+	cr := &boltDBCouponRepository{db}
+
+	if err := cr.init(); err != nil {
+		log.Fatalln(err)
+	}
+
+	return cr
+}
+
+func (cr *boltDBCouponRepository) SaveCoupon(ctx context.Context, coupon *models.Coupon) (*models.Coupon, error) {
+	err := cr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketCoupon))
+
+		data, err := json.Marshal(coupon)
+		if err != nil {
+			return err
+		}
+
+		return tb.Put([]byte(coupon.Code), data)
+	})
+	return coupon, err
+}
+
+func (cr *boltDBCouponRepository) GetCouponByCode(ctx context.Context, code string) (*models.Coupon, error) {
+	var coupon *models.Coupon
+	err := cr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketCoupon))
+
+		v := tb.Get([]byte(code))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &coupon)
+	})
+	return coupon, err
+}
+
+func (cr *boltDBCouponRepository) FetchAllCoupons(ctx context.Context) ([]*models.Coupon, error) {
+	var coupons = make([]*models.Coupon, 0)
+	err := cr.db.View(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketCoupon))
+
+		return tb.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil
+			}
+			var coupon models.Coupon
+			err := json.Unmarshal(v, &coupon)
+			if err != nil {
+				return err
+			}
+			coupons = append(coupons, &coupon)
+			return nil
+		})
+	})
+	return coupons, err
+}
+
+func (cr *boltDBCouponRepository) DeleteCoupon(ctx context.Context, code string) (*models.Coupon, error) {
+	var existing *models.Coupon
+	err := cr.db.Update(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketCoupon))
+
+		v := tb.Get([]byte(code))
+		if v == nil {
+			return nil
+		}
+		err := json.Unmarshal(v, &existing)
+		if err != nil {
+			return err
+		}
+
+		return tb.Delete([]byte(code))
+	})
+	return existing, err
+}
@@ -0,0 +1,52 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/aweris/stp/internal/models"
+	salesRepository "github.com/aweris/stp/internal/sales/repository"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBoltDBCouponRepository_SaveCoupon_ThanShouldGetCouponByCode(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := salesRepository.NewBoltDBCouponRepository(db.BoltDB)
+
+	coupon := &models.Coupon{Code: "SUMMER10", DiscountId: uuid.NewV1(), MaxUses: 5}
+
+	_, err := r.SaveCoupon(context.Background(), coupon)
+	assert.NoError(t, err, "failed to add coupon")
+
+	find, err := r.GetCouponByCode(context.Background(), coupon.Code)
+	assert.NoError(t, err, "failed to get coupon")
+	assert.Equal(t, coupon.DiscountId, find.DiscountId)
+	assert.Equal(t, coupon.MaxUses, find.MaxUses)
+
+	list, err := r.FetchAllCoupons(context.Background())
+	assert.NoError(t, err, "failed to fetch coupons")
+	assert.Equal(t, 1, len(list))
+}
+
+func TestBoltDBCouponRepository_DeleteCoupon_ThanShouldDeleteCouponAndReturnDeletedCoupon(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := salesRepository.NewBoltDBCouponRepository(db.BoltDB)
+
+	coupon := &models.Coupon{Code: "SUMMER10", DiscountId: uuid.NewV1()}
+
+	_, err := r.SaveCoupon(context.Background(), coupon)
+	assert.NoError(t, err, "failed to add coupon")
+
+	deleted, err := r.DeleteCoupon(context.Background(), coupon.Code)
+	assert.NoError(t, err, "failed to delete coupon")
+	assert.Equal(t, coupon.Code, deleted.Code)
+
+	find, err := r.GetCouponByCode(context.Background(), coupon.Code)
+	assert.NoError(t, err, "failed to get coupon")
+	assert.Nil(t, find)
+}
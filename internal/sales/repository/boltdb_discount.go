@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/sales"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"go.etcd.io/bbolt"
+	"log"
+)
+
+const (
+	bucketDiscount = "sales_discount"
+)
+
+type boltDBDiscountRepository struct {
+	db *storage.BoltDB
+}
+
+func (dr *boltDBDiscountRepository) init() error {
+	return dr.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketDiscount))
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// NewBoltDBDiscountRepository creates discount repository for bolt db
+func NewBoltDBDiscountRepository(db *storage.BoltDB) sales.DiscountRepository {
+	dr := &boltDBDiscountRepository{db}
+
+	if err := dr.init(); err != nil {
+		log.Fatalln(err)
+	}
+
+	return dr
+}
+
+func (dr *boltDBDiscountRepository) SaveDiscount(ctx context.Context, discount *models.Discount) (*models.Discount, error) {
+	err := dr.db.Update(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketDiscount))
+
+		data, err := json.Marshal(discount)
+		if err != nil {
+			return err
+		}
+
+		return tb.Put(discount.Id.Bytes(), data)
+	})
+	return discount, err
+}
+
+func (dr *boltDBDiscountRepository) GetDiscountByID(ctx context.Context, discountId uuid.UUID) (*models.Discount, error) {
+	var discount *models.Discount
+	err := dr.db.View(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketDiscount))
+
+		v := tb.Get(discountId.Bytes())
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &discount)
+	})
+	return discount, err
+}
+
+func (dr *boltDBDiscountRepository) FetchAllDiscounts(ctx context.Context) ([]*models.Discount, error) {
+	var discounts = make([]*models.Discount, 0)
+	err := dr.db.View(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketDiscount))
+
+		return tb.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil
+			}
+			var discount models.Discount
+			err := json.Unmarshal(v, &discount)
+			if err != nil {
+				return err
+			}
+			discounts = append(discounts, &discount)
+			return nil
+		})
+	})
+	return discounts, err
+}
+
+func (dr *boltDBDiscountRepository) DeleteDiscount(ctx context.Context, discountId uuid.UUID) (*models.Discount, error) {
+	var existing *models.Discount
+	err := dr.db.Update(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketDiscount))
+
+		v := tb.Get(discountId.Bytes())
+		if v == nil {
+			return nil
+		}
+		err := json.Unmarshal(v, &existing)
+		if err != nil {
+			return err
+		}
+
+		return tb.Delete(discountId.Bytes())
+	})
+	return existing, err
+}
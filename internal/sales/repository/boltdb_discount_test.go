@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/aweris/stp/internal/models"
+	salesRepository "github.com/aweris/stp/internal/sales/repository"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBoltDBDiscountRepository_SaveDiscount_ThanShouldGetDiscountByID(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := salesRepository.NewBoltDBDiscountRepository(db.BoltDB)
+
+	discount := &models.Discount{Id: uuid.NewV1(), Name: "Summer Sale", Kind: models.DiscountKindPercentage, Scope: models.DiscountScopeBasket, Rate: decimal.NewFromFloat32(10)}
+
+	_, err := r.SaveDiscount(context.Background(), discount)
+	assert.NoError(t, err, "failed to add discount")
+
+	find, err := r.GetDiscountByID(context.Background(), discount.Id)
+	assert.NoError(t, err, "failed to get discount")
+	assert.Equal(t, discount.Id, find.Id)
+	assert.Equal(t, discount.Scope, find.Scope)
+	assert.True(t, discount.Rate.Equal(find.Rate))
+
+	list, err := r.FetchAllDiscounts(context.Background())
+	assert.NoError(t, err, "failed to fetch discounts")
+	assert.Equal(t, 1, len(list))
+}
+
+func TestBoltDBDiscountRepository_DeleteDiscount_ThanShouldDeleteDiscountAndReturnDeletedDiscount(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := salesRepository.NewBoltDBDiscountRepository(db.BoltDB)
+
+	discount := &models.Discount{Id: uuid.NewV1(), Name: "Summer Sale", Kind: models.DiscountKindPercentage, Scope: models.DiscountScopeBasket, Rate: decimal.NewFromFloat32(10)}
+
+	_, err := r.SaveDiscount(context.Background(), discount)
+	assert.NoError(t, err, "failed to add discount")
+
+	deleted, err := r.DeleteDiscount(context.Background(), discount.Id)
+	assert.NoError(t, err, "failed to delete discount")
+	assert.Equal(t, discount.Id, deleted.Id)
+
+	find, err := r.GetDiscountByID(context.Background(), discount.Id)
+	assert.NoError(t, err, "failed to get discount")
+	assert.Nil(t, find)
+}
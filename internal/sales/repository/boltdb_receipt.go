@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"github.com/aweris/stp/internal/models"
 	"github.com/aweris/stp/internal/sales"
@@ -9,10 +11,15 @@ import (
 	"github.com/satori/go.uuid"
 	"go.etcd.io/bbolt"
 	"log"
+	"time"
 )
 
 const (
-	bucketReceipt = "sales_receipt"
+	bucketReceipt          = "sales_receipt"
+	bucketReceiptMeta      = "_meta"
+	bucketReceiptIdx       = "index"
+	bucketReceiptIdxBasket = "idx_receipt_basket" // index of receipt ids by basket id
+	bucketReceiptIdxTime   = "idx_receipt_time"   // index of receipt ids ordered by issue time
 )
 
 type boltDBReceiptRepository struct {
@@ -21,7 +28,27 @@ type boltDBReceiptRepository struct {
 
 func (rr *boltDBReceiptRepository) init() error {
 	return rr.db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(bucketReceipt))
+		tb, err := tx.CreateBucketIfNotExists([]byte(bucketReceipt))
+		if err != nil {
+			return err
+		}
+
+		mt, err := tb.CreateBucketIfNotExists([]byte(bucketReceiptMeta))
+		if err != nil {
+			return err
+		}
+
+		ib, err := mt.CreateBucketIfNotExists([]byte(bucketReceiptIdx))
+		if err != nil {
+			return err
+		}
+
+		_, err = ib.CreateBucketIfNotExists([]byte(bucketReceiptIdxBasket))
+		if err != nil {
+			return err
+		}
+
+		_, err = ib.CreateBucketIfNotExists([]byte(bucketReceiptIdxTime))
 		if err != nil {
 			return err
 		}
@@ -40,7 +67,7 @@ func NewBoltDBReceiptRepository(db *storage.BoltDB) sales.ReceiptRepository {
 }
 
 func (rr *boltDBReceiptRepository) SaveReceipt(ctx context.Context, receipt *models.Receipt) (*models.Receipt, error) {
-	err := rr.db.Update(func(tx *bolt.Tx) error {
+	err := rr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketReceipt))
 
 		data, err := json.Marshal(receipt)
@@ -48,14 +75,19 @@ func (rr *boltDBReceiptRepository) SaveReceipt(ctx context.Context, receipt *mod
 			return err
 		}
 
-		return tb.Put(receipt.Id.Bytes(), data)
+		err = tb.Put(receipt.Id.Bytes(), data)
+		if err != nil {
+			return err
+		}
+
+		return indexReceipt(tb, receipt)
 	})
 	return receipt, err
 }
 
 func (rr *boltDBReceiptRepository) GetReceiptByID(ctx context.Context, receiptId uuid.UUID) (*models.Receipt, error) {
 	var r *models.Receipt
-	err := rr.db.View(func(tx *bolt.Tx) error {
+	err := rr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketReceipt))
 
 		v := tb.Get(receiptId.Bytes())
@@ -71,6 +103,26 @@ func (rr *boltDBReceiptRepository) GetReceiptByID(ctx context.Context, receiptId
 	return r, err
 }
 
+// GetReceiptByBasketID returns receipt of the closed basket, nil if basket doesn't have a receipt
+func (rr *boltDBReceiptRepository) GetReceiptByBasketID(ctx context.Context, basketId uuid.UUID) (*models.Receipt, error) {
+	var r *models.Receipt
+	err := rr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketReceipt))
+
+		id := idxBucket(tb, bucketReceiptIdxBasket).Get(basketId.Bytes())
+		if id == nil {
+			return nil
+		}
+
+		v := tb.Get(id)
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &r)
+	})
+	return r, err
+}
+
 func (rr *boltDBReceiptRepository) FetchAllReceipts(ctx context.Context) ([]*models.Receipt, error) {
 	var rs = make([]*models.Receipt, 0)
 	err := rr.db.View(func(tx *bolt.Tx) error {
@@ -91,3 +143,72 @@ func (rr *boltDBReceiptRepository) FetchAllReceipts(ctx context.Context) ([]*mod
 	})
 	return rs, err
 }
+
+// FetchReceiptsIssuedBetween returns receipts issued in [from, to) ordered by issue time, zero to means no upper bound.
+// Receipts without issue time are not indexed and never returned.
+func (rr *boltDBReceiptRepository) FetchReceiptsIssuedBetween(ctx context.Context, from, to time.Time) ([]*models.Receipt, error) {
+	var rs = make([]*models.Receipt, 0)
+	err := rr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(bucketReceipt))
+		c := idxBucket(tb, bucketReceiptIdxTime).Cursor()
+
+		var k, v []byte
+		if from.IsZero() {
+			k, v = c.First()
+		} else {
+			k, v = c.Seek(issuedKey(from, uuid.Nil)[:issuedTimeSize])
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			if !to.IsZero() && bytes.Compare(k[:issuedTimeSize], issuedKey(to, uuid.Nil)[:issuedTimeSize]) >= 0 {
+				break
+			}
+
+			data := tb.Get(v)
+			if data == nil {
+				continue
+			}
+			var r models.Receipt
+			err := json.Unmarshal(data, &r)
+			if err != nil {
+				return err
+			}
+			rs = append(rs, &r)
+		}
+		return nil
+	})
+	return rs, err
+}
+
+// indexReceipt adds receipt to basket and time indexes, receipts without basket or issue time are not indexed for them
+func indexReceipt(tb *bolt.Bucket, receipt *models.Receipt) error {
+	if !receipt.IssuedAt.IsZero() {
+		err := idxBucket(tb, bucketReceiptIdxTime).Put(issuedKey(receipt.IssuedAt, receipt.Id), receipt.Id.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	if receipt.BasketId == uuid.Nil {
+		return nil
+	}
+	return idxBucket(tb, bucketReceiptIdxBasket).Put(receipt.BasketId.Bytes(), receipt.Id.Bytes())
+}
+
+// idxBucket returns the index bucket with given name under receipt bucket
+func idxBucket(tb *bolt.Bucket, name string) *bolt.Bucket {
+	return tb.Bucket([]byte(bucketReceiptMeta)).Bucket([]byte(bucketReceiptIdx)).Bucket([]byte(name))
+}
+
+// issuedTimeSize is the size of issue time prefix of time index keys, seconds and nanoseconds of the time
+const issuedTimeSize = 8 + 4
+
+// issuedKey returns key of the receipt in time index, issue time first for keeping receipts in order and receipt id
+// for receipts issued at the same time. Seconds are stored with sign bit flipped, so times before 1970 sort before
+// later ones.
+func issuedKey(at time.Time, receiptId uuid.UUID) []byte {
+	b := make([]byte, issuedTimeSize, issuedTimeSize+uuid.Size)
+	binary.BigEndian.PutUint64(b, uint64(at.Unix())^(1<<63))
+	binary.BigEndian.PutUint32(b[8:], uint32(at.Nanosecond()))
+	return append(b, receiptId.Bytes()...)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.etcd.io/bbolt"
 	"testing"
+	"time"
 )
 
 const (
@@ -94,6 +95,39 @@ func TestBoltDBReceiptRepository_GetReceiptByID(t *testing.T) {
 	assert.NotNil(t, find)
 }
 
+func TestBoltDBReceiptRepository_GetReceiptByID_ThenShouldReturnTaxBreakdown(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := salesRepository.NewBoltDBReceiptRepository(db.BoltDB)
+
+	taxId := uuid.NewV1()
+
+	re := &models.Receipt{
+		Id: uuid.NewV1(),
+		TaxBreakdown: []*models.TaxLine{
+			{
+				TaxId:  taxId,
+				Name:   "Basic Sales Tax",
+				Rate:   decimal.NewFromFloat32(10),
+				Amount: decimal.NewFromFloat32(1),
+			},
+		},
+		TotalTax:   decimal.NewFromFloat32(1),
+		TotalPrice: decimal.NewFromFloat32(10),
+		TotalGross: decimal.NewFromFloat32(11),
+	}
+
+	re, err := r.SaveReceipt(context.Background(), re)
+	assert.NoError(t, err)
+
+	find, err := r.GetReceiptByID(context.Background(), re.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(find.TaxBreakdown))
+	assert.Equal(t, taxId, find.TaxBreakdown[0].TaxId)
+	assert.True(t, find.TaxBreakdown[0].Amount.Equal(decimal.NewFromFloat32(1)))
+}
+
 func TestBoltDBReceiptRepository_GetReceiptByID_WhenIdNotExistInDB_ThenShouldNotReturnErr(t *testing.T) {
 	db := storage.NewTestDB()
 	defer db.Close()
@@ -141,3 +175,101 @@ func TestBoltDBReceiptRepository_FetchAllReceipts(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, 1, len(list))
 }
+
+func TestBoltDBReceiptRepository_GetReceiptByBasketID_ThanShouldReturnReceiptOfBasket(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := salesRepository.NewBoltDBReceiptRepository(db.BoltDB)
+
+	receipt := &models.Receipt{Id: uuid.NewV1(), BasketId: uuid.NewV1()}
+
+	_, err := r.SaveReceipt(context.Background(), receipt)
+	assert.NoError(t, err)
+
+	find, err := r.GetReceiptByBasketID(context.Background(), receipt.BasketId)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.Id, find.Id)
+
+	find, err = r.GetReceiptByBasketID(context.Background(), uuid.NewV1())
+	assert.NoError(t, err)
+	assert.Nil(t, find)
+}
+
+func TestBoltDBReceiptRepository_FetchReceiptsIssuedBetween_ThanShouldReturnReceiptsInRangeOrderedByTime(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := salesRepository.NewBoltDBReceiptRepository(db.BoltDB)
+
+	day := time.Date(2019, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	receipts := []*models.Receipt{
+		{Id: uuid.NewV1(), IssuedAt: day.Add(-time.Minute)},
+		{Id: uuid.NewV1(), IssuedAt: day.Add(18 * time.Hour)},
+		{Id: uuid.NewV1(), IssuedAt: day},
+		{Id: uuid.NewV1(), IssuedAt: day.Add(24 * time.Hour)},
+		{Id: uuid.NewV1()},
+	}
+
+	for _, receipt := range receipts {
+		_, err := r.SaveReceipt(context.Background(), receipt)
+		assert.NoError(t, err)
+	}
+
+	list, err := r.FetchReceiptsIssuedBetween(context.Background(), day, day.Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, receipts[2].Id, list[0].Id)
+	assert.Equal(t, receipts[1].Id, list[1].Id)
+
+	list, err = r.FetchReceiptsIssuedBetween(context.Background(), day, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(list))
+
+	list, err = r.FetchReceiptsIssuedBetween(context.Background(), time.Time{}, day)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, receipts[0].Id, list[0].Id)
+}
+
+func TestBoltDBReceiptRepository_FetchReceiptsIssuedBetween_WhenIssuedBefore1970_ThanShouldReturnReceiptsOrderedByTime(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := salesRepository.NewBoltDBReceiptRepository(db.BoltDB)
+
+	epoch := time.Unix(0, 0).UTC()
+
+	receipts := []*models.Receipt{
+		{Id: uuid.NewV1(), IssuedAt: epoch.Add(time.Hour)},
+		{Id: uuid.NewV1(), IssuedAt: epoch.Add(-time.Hour)},
+		{Id: uuid.NewV1(), IssuedAt: epoch.AddDate(-10, 0, 0)},
+		{Id: uuid.NewV1(), IssuedAt: time.Date(1500, 1, 1, 0, 0, 0, 1, time.UTC)},
+		{Id: uuid.NewV1(), IssuedAt: time.Date(2500, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, receipt := range receipts {
+		_, err := r.SaveReceipt(context.Background(), receipt)
+		assert.NoError(t, err)
+	}
+
+	list, err := r.FetchReceiptsIssuedBetween(context.Background(), time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(list))
+	assert.Equal(t, receipts[3].Id, list[0].Id)
+	assert.Equal(t, receipts[2].Id, list[1].Id)
+	assert.Equal(t, receipts[1].Id, list[2].Id)
+	assert.Equal(t, receipts[0].Id, list[3].Id)
+	assert.Equal(t, receipts[4].Id, list[4].Id)
+
+	list, err = r.FetchReceiptsIssuedBetween(context.Background(), epoch.AddDate(-1, 0, 0), epoch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, receipts[1].Id, list[0].Id)
+
+	list, err = r.FetchReceiptsIssuedBetween(context.Background(), time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC), epoch.AddDate(-1, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, receipts[3].Id, list[0].Id)
+}
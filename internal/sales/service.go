@@ -4,15 +4,32 @@ import (
 	"context"
 	"github.com/aweris/stp/internal/models"
 	"github.com/satori/go.uuid"
+	"time"
 )
 
 type SalesService interface {
-	CreateBasket(ctx context.Context) (uuid.UUID, error)
+	CreateBasket(ctx context.Context, zoneId uuid.UUID) (uuid.UUID, error)
 	GetBasketByID(ctx context.Context, basketId uuid.UUID) (*models.Basket, error)
-	AddItem(ctx context.Context, basketId uuid.UUID, itemId uuid.UUID, itemCount int) (error)
-	RemoveItem(ctx context.Context, basketId uuid.UUID, itemId uuid.UUID, itemCount int) (error)
-	CancelBasket(ctx context.Context, basketId uuid.UUID) (error)
+	AddItem(ctx context.Context, basketId uuid.UUID, itemId uuid.UUID, itemCount int) (*models.Basket, error)
+	RemoveItem(ctx context.Context, basketId uuid.UUID, itemId uuid.UUID, itemCount int) (*models.Basket, error)
+	AttachCertificate(ctx context.Context, basketId uuid.UUID, cert *models.ExemptionCertificate) (*models.Basket, error)
+	ApplyCoupon(ctx context.Context, basketId uuid.UUID, code string) (*models.Basket, error)
+	Reprice(ctx context.Context, basketId uuid.UUID) (*models.Basket, error)
+	CancelBasket(ctx context.Context, basketId uuid.UUID) (*models.Basket, error)
 	CloseBasket(ctx context.Context, basketId uuid.UUID) (*models.Receipt, error)
 	GetReceiptByID(ctx context.Context, receiptId uuid.UUID) (*models.Receipt, error)
+	GetReceiptByBasketID(ctx context.Context, basketId uuid.UUID) (*models.Receipt, error)
 	FetchAllReceipts(ctx context.Context) ([]*models.Receipt, error)
+	FetchReceiptsIssuedBetween(ctx context.Context, from, to time.Time) ([]*models.Receipt, error)
+	Quote(ctx context.Context, zoneId uuid.UUID, lines []*models.QuoteLine) (*models.Quote, error)
+
+	CreateDiscount(ctx context.Context, discount *models.Discount) (*models.Discount, error)
+	GetDiscountByID(ctx context.Context, discountId uuid.UUID) (*models.Discount, error)
+	FetchAllDiscounts(ctx context.Context) ([]*models.Discount, error)
+	DeleteDiscount(ctx context.Context, discountId uuid.UUID) (*models.Discount, error)
+
+	CreateCoupon(ctx context.Context, coupon *models.Coupon) (*models.Coupon, error)
+	GetCouponByCode(ctx context.Context, code string) (*models.Coupon, error)
+	FetchAllCoupons(ctx context.Context) ([]*models.Coupon, error)
+	DeleteCoupon(ctx context.Context, code string) (*models.Coupon, error)
 }
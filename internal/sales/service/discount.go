@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"github.com/aweris/stp/internal/models"
+	"github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+// places is number of decimal places discounts and discounted prices rounded to
+const places = 2
+
+var (
+	hundred = decimal.New(100, 0)
+	cent    = decimal.New(1, -places)
+)
+
+// pricedUnits is number of units of an item sold with the same price
+type pricedUnits struct {
+	count int
+	price decimal.Decimal
+}
+
+// applyDiscounts calculates discounts of given items on their shelf prices. Item, category and buy x get y discounts applied
+// first, basket discounts applied on the rest and shared by items in proportion to their remaining prices. Returns
+// discount lines and total discount of each item.
+func applyDiscounts(items []*models.BasketItem, discounts []*models.Discount) ([]*models.DiscountLine, map[uuid.UUID]decimal.Decimal) {
+	// sorted for sharing basket discounts same way in every call
+	sorted := make([]*models.BasketItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Id.String() < sorted[j].Id.String()
+	})
+
+	remaining := make(map[uuid.UUID]decimal.Decimal, len(sorted))
+	for _, bi := range sorted {
+		remaining[bi.Id] = shelfPrice(bi).Mul(decimal.New(int64(bi.Count), 0))
+	}
+
+	lines := make([]*models.DiscountLine, 0)
+	perItem := make(map[uuid.UUID]decimal.Decimal, len(sorted))
+
+	for _, d := range discounts {
+		if d.Scope == models.DiscountScopeBasket {
+			continue
+		}
+		for _, bi := range sorted {
+			if !d.Matches(bi.InventoryItem) {
+				continue
+			}
+
+			amount := minDecimal(lineDiscount(d, bi), remaining[bi.Id])
+			if !amount.IsPositive() {
+				continue
+			}
+
+			remaining[bi.Id] = remaining[bi.Id].Sub(amount)
+			perItem[bi.Id] = perItem[bi.Id].Add(amount)
+			lines = append(lines, &models.DiscountLine{DiscountId: d.Id, Name: d.Name, ItemId: bi.Id, Amount: amount})
+		}
+	}
+
+	for _, d := range discounts {
+		if d.Scope != models.DiscountScopeBasket {
+			continue
+		}
+
+		shares := shareDiscount(d, sorted, remaining)
+
+		total := decimal.Zero
+		for id, share := range shares {
+			remaining[id] = remaining[id].Sub(share)
+			perItem[id] = perItem[id].Add(share)
+			total = total.Add(share)
+		}
+
+		if total.IsPositive() {
+			lines = append(lines, &models.DiscountLine{DiscountId: d.Id, Name: d.Name, Amount: total})
+		}
+	}
+
+	return lines, perItem
+}
+
+// basketDiscounts filters discounts applicable to a basket, coupon only discounts applied only with their coupons
+func basketDiscounts(discounts []*models.Discount, coupons []*models.Coupon) []*models.Discount {
+	couponed := make(map[uuid.UUID]bool, len(coupons))
+	for _, c := range coupons {
+		couponed[c.DiscountId] = true
+	}
+
+	applicable := make([]*models.Discount, 0, len(discounts))
+	for _, d := range discounts {
+		if !d.CouponOnly || couponed[d.Id] {
+			applicable = append(applicable, d)
+		}
+	}
+	return applicable
+}
+
+// lineDiscount returns discount of an item or category discount for all units of the item
+func lineDiscount(d *models.Discount, bi *models.BasketItem) decimal.Decimal {
+	count := decimal.New(int64(bi.Count), 0)
+
+	switch {
+	case d.Scope == models.DiscountScopeBuyXGetY:
+		free := bi.Count / (d.Buy + d.Get) * d.Get
+		return shelfPrice(bi).Mul(decimal.New(int64(free), 0))
+	case d.Kind == models.DiscountKindFixed:
+		return d.Amount.Mul(count)
+	default:
+		return shelfPrice(bi).Mul(count).Mul(d.Rate).Div(hundred).Round(places)
+	}
+}
+
+// shareDiscount calculates basket discount on remaining prices of items and shares it by items in proportion to their
+// remaining prices, last item takes the rest of the discount left from rounding
+func shareDiscount(d *models.Discount, items []*models.BasketItem, remaining map[uuid.UUID]decimal.Decimal) map[uuid.UUID]decimal.Decimal {
+	subtotal := decimal.Zero
+	priced := make([]*models.BasketItem, 0, len(items))
+	for _, bi := range items {
+		if remaining[bi.Id].IsPositive() {
+			subtotal = subtotal.Add(remaining[bi.Id])
+			priced = append(priced, bi)
+		}
+	}
+
+	shares := make(map[uuid.UUID]decimal.Decimal, len(priced))
+	if !subtotal.IsPositive() {
+		return shares
+	}
+
+	amount := d.Amount
+	if d.Kind == models.DiscountKindPercentage {
+		amount = subtotal.Mul(d.Rate).Div(hundred).Round(places)
+	}
+	amount = minDecimal(amount, subtotal)
+
+	left := amount
+	for i, bi := range priced {
+		share := left
+		if i < len(priced)-1 {
+			share = amount.Mul(remaining[bi.Id]).Div(subtotal).Round(places)
+		}
+		share = minDecimal(share, remaining[bi.Id], left)
+
+		shares[bi.Id] = share
+		left = left.Sub(share)
+	}
+	return shares
+}
+
+// discountedItems prices items with their discounts shared by units, so taxes can be calculated on discounted prices.
+// Discounted prices are shelf prices like the listed ones, so taxes of tax inclusive items are taken out of them. Unit
+// prices are kept in cents, the rest of the sharing is added to price of one unit priced separately.
+func (ss *salesService) discountedItems(ctx context.Context, scope models.TaxScope, items []*models.BasketItem, perItem map[uuid.UUID]decimal.Decimal) ([]*models.BasketItem, error) {
+	discounted := make([]*models.BasketItem, 0, len(items))
+
+	for _, bi := range items {
+		discount := perItem[bi.Id]
+		if discount.IsZero() {
+			discounted = append(discounted, bi)
+			continue
+		}
+
+		total := shelfPrice(bi).Mul(decimal.New(int64(bi.Count), 0)).Sub(discount)
+		unit := total.Div(decimal.New(int64(bi.Count), 0)).Div(cent).Floor().Mul(cent)
+		rest := total.Sub(unit.Mul(decimal.New(int64(bi.Count-1), 0)))
+
+		units := []pricedUnits{{count: bi.Count, price: unit}}
+		if !rest.Equal(unit) {
+			units = []pricedUnits{{count: bi.Count - 1, price: unit}, {count: 1, price: rest}}
+		}
+
+		for _, u := range units {
+			if u.count == 0 {
+				continue
+			}
+
+			item := *bi.InventoryItem
+			item.Price = u.price
+			item.TaxInclusive = bi.TaxInclusive
+
+			si, err := ss.taxService.GetSaleItem(ctx, &item, scope)
+			if err != nil {
+				return nil, err
+			}
+			discounted = append(discounted, &models.BasketItem{SaleItem: si, Count: u.count})
+		}
+	}
+	return discounted, nil
+}
+
+// shelfPrice returns unit price of the item discounts calculated on, the listed price which includes taxes of tax
+// inclusive items and excludes taxes of the others. Discounts reduce the amount paid for the item in both cases.
+func shelfPrice(bi *models.BasketItem) decimal.Decimal {
+	return bi.Price
+}
+
+// sumDiscounts returns total amount of discount lines
+func sumDiscounts(lines []*models.DiscountLine) decimal.Decimal {
+	total := decimal.Zero
+	for _, l := range lines {
+		total = total.Add(l.Amount)
+	}
+	return total
+}
+
+func minDecimal(first decimal.Decimal, rest ...decimal.Decimal) decimal.Decimal {
+	min := first
+	for _, d := range rest {
+		if d.LessThan(min) {
+			min = d
+		}
+	}
+	return min
+}
@@ -9,28 +9,46 @@ import (
 	"github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
 )
 
 type salesService struct {
-	basketRepo  sales.BasketRepository
-	receiptRepo sales.ReceiptRepository
+	basketRepo   sales.BasketRepository
+	receiptRepo  sales.ReceiptRepository
+	discountRepo sales.DiscountRepository
+	couponRepo   sales.CouponRepository
+	transactor   sales.Transactor
 
 	invService inventory.InventoryService
 	taxService taxes.TaxService
+
+	policy    models.DiscountTaxPolicy
+	repricing models.RepricingPolicy
 }
 
-func NewSalesService(basketRepo sales.BasketRepository, receiptRepo sales.ReceiptRepository, invService inventory.InventoryService, taxService taxes.TaxService) sales.SalesService {
-	return &salesService{basketRepo: basketRepo, receiptRepo: receiptRepo, taxService: taxService, invService: invService}
+// NewSalesService creates sales service with given repositories, transactor spanning them, services, policy for taxing
+// discounted sales and policy for repricing basket items
+func NewSalesService(basketRepo sales.BasketRepository, receiptRepo sales.ReceiptRepository, discountRepo sales.DiscountRepository, couponRepo sales.CouponRepository, transactor sales.Transactor, invService inventory.InventoryService, taxService taxes.TaxService, policy models.DiscountTaxPolicy, repricing models.RepricingPolicy) sales.SalesService {
+	return &salesService{basketRepo: basketRepo, receiptRepo: receiptRepo, discountRepo: discountRepo, couponRepo: couponRepo, transactor: transactor, taxService: taxService, invService: invService, policy: policy, repricing: repricing}
 }
 
-func (ss *salesService) CreateBasket(ctx context.Context) (uuid.UUID, error) {
+// CreateBasket creates an open basket for a sale in given zone, nil zone means the sale is not in a specific zone
+func (ss *salesService) CreateBasket(ctx context.Context, zoneId uuid.UUID) (uuid.UUID, error) {
+	err := ss.checkZone(ctx, zoneId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	b := &models.Basket{
-		Id:    uuid.NewV1(),
-		Items: make(map[uuid.UUID]*models.BasketItem, 0),
-		State: models.BasketStateOpened,
+		Id:        uuid.NewV1(),
+		Items:     make(map[uuid.UUID]*models.BasketItem, 0),
+		State:     models.BasketStateOpened,
+		CreatedAt: time.Now(),
+		ZoneId:    zoneId,
 	}
 
-	_, err := ss.basketRepo.SaveBasket(ctx, b)
+	_, err = ss.basketRepo.SaveBasket(ctx, b)
 	if err != nil {
 		log.WithError(err).Error("failed to save basket")
 		return uuid.Nil, err
@@ -47,45 +65,50 @@ func (ss *salesService) GetBasketByID(ctx context.Context, basketId uuid.UUID) (
 	return ss.basketRepo.GetBasketByID(ctx, basketId)
 }
 
-func (ss *salesService) AddItem(ctx context.Context, basketId uuid.UUID, itemId uuid.UUID, itemCount int) (error) {
+func (ss *salesService) AddItem(ctx context.Context, basketId uuid.UUID, itemId uuid.UUID, itemCount int) (*models.Basket, error) {
 	if basketId == uuid.Nil {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(sales.ErrInvalidBasketId).Error("missing basketId")
-		return sales.ErrInvalidBasketId
+		return nil, sales.ErrInvalidBasketId
 	}
 	if itemId == uuid.Nil {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(inventory.ErrInvalidItemId).Error("missing itemId")
-		return inventory.ErrInvalidItemId
+		return nil, inventory.ErrInvalidItemId
 	}
 	if itemCount <= 0 {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(sales.ErrInvalidItemCount).Error("invalid item count")
-		return sales.ErrInvalidItemCount
+		return nil, sales.ErrInvalidItemCount
 	}
 
 	item, err := ss.invService.GetItemByID(ctx, itemId)
 	if err != nil {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(err).Error("failed to get item with given id")
-		return err
-	}
-
-	si, err := ss.taxService.GetSaleItem(ctx, item)
-	if err != nil {
-		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(err).Error("failed to get sale item")
-		return err
+		return nil, err
 	}
 
 	basket, err := ss.basketRepo.GetBasketByID(ctx, basketId)
 	if err != nil {
-		log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).WithError(err).Error("failed to get basket")
-		return err
+		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(err).Error("failed to get basket")
+		return nil, err
 	}
 	if basket == nil {
-		log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).WithError(sales.ErrInvalidBasketId).Error("failed to find basket with given id")
-		return sales.ErrInvalidBasketId
+		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(sales.ErrInvalidBasketId).Error("failed to find basket with given id")
+		return nil, sales.ErrInvalidBasketId
 	}
 
 	if basket.State != models.BasketStateOpened {
-		log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).WithError(sales.ErrBasketNotOpen).Error("basket is not available")
-		return sales.ErrBasketNotOpen
+		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(sales.ErrBasketNotOpen).Error("basket is not available")
+		return nil, sales.ErrBasketNotOpen
+	}
+
+	err = checkVersion(ctx, basket)
+	if err != nil {
+		return nil, err
+	}
+
+	si, err := ss.taxService.GetSaleItem(ctx, item, basket.TaxScope())
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(err).Error("failed to get sale item")
+		return nil, err
 	}
 
 	bi := basket.Items[si.Id]
@@ -96,69 +119,85 @@ func (ss *salesService) AddItem(ctx context.Context, basketId uuid.UUID, itemId
 		bi = &models.BasketItem{
 			SaleItem: si,
 			Count:    itemCount,
+			PricedAt: time.Now(),
 		}
 	}
 
+	// prices are not locked, existing line takes current price and taxes as well
+	if ss.repricing == models.RepricingAtClose {
+		bi.SaleItem = si
+		bi.PricedAt = time.Now()
+	}
+
 	basket.Items[si.Id] = bi
 
-	ss.basketRepo.SaveBasket(ctx, basket)
+	_, err = ss.basketRepo.SaveBasket(ctx, basket)
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).WithError(err).Error("failed to save basket")
+		return nil, err
+	}
 
 	log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).Info("item added/updated in basket")
 
-	return nil
+	return basket, nil
 }
 
-func (ss *salesService) RemoveItem(ctx context.Context, basketId uuid.UUID, itemId uuid.UUID, itemCount int) (error) {
+func (ss *salesService) RemoveItem(ctx context.Context, basketId uuid.UUID, itemId uuid.UUID, itemCount int) (*models.Basket, error) {
 	if basketId == uuid.Nil {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(sales.ErrInvalidBasketId).Error("missing basketId")
-		return sales.ErrInvalidBasketId
+		return nil, sales.ErrInvalidBasketId
 	}
 	if itemId == uuid.Nil {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(inventory.ErrInvalidItemId).Error("missing itemId")
-		return inventory.ErrInvalidItemId
+		return nil, inventory.ErrInvalidItemId
 	}
 	if itemCount <= 0 {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(sales.ErrInvalidItemCount).Error("invalid item count")
-		return sales.ErrInvalidItemCount
+		return nil, sales.ErrInvalidItemCount
 	}
 
 	item, err := ss.invService.GetItemByID(ctx, itemId)
 	if err != nil {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(err).Error("failed to get item with given id")
-		return err
-	}
-
-	si, err := ss.taxService.GetSaleItem(ctx, item)
-	if err != nil {
-		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(err).Error("failed to get sale item")
-		return err
+		return nil, err
 	}
 
 	basket, err := ss.basketRepo.GetBasketByID(ctx, basketId)
 	if err != nil {
-		log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).WithError(err).Error("failed to get basket")
-		return err
+		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(err).Error("failed to get basket")
+		return nil, err
 	}
 	if basket == nil {
-		log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).WithError(sales.ErrInvalidBasketId).Error("failed to find basket with given id")
-		return sales.ErrInvalidBasketId
+		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(sales.ErrInvalidBasketId).Error("failed to find basket with given id")
+		return nil, sales.ErrInvalidBasketId
 	}
 
 	if basket.State != models.BasketStateOpened {
-		log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).WithError(sales.ErrBasketNotOpen).Error("basket is not available")
-		return sales.ErrBasketNotOpen
+		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(sales.ErrBasketNotOpen).Error("basket is not available")
+		return nil, sales.ErrBasketNotOpen
+	}
+
+	err = checkVersion(ctx, basket)
+	if err != nil {
+		return nil, err
+	}
+
+	si, err := ss.taxService.GetSaleItem(ctx, item, basket.TaxScope())
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId, "item": item, "itemCount": itemCount}).WithError(err).Error("failed to get sale item")
+		return nil, err
 	}
 
 	bi := basket.Items[si.Id]
 
 	if bi == nil {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(inventory.ErrInvalidItemId).Error("missing itemId in basket")
-		return inventory.ErrInvalidItemId
+		return nil, inventory.ErrInvalidItemId
 	}
 
 	if bi.Count < itemCount {
 		log.WithFields(log.Fields{"basketId": basketId, "itemId": itemId, "itemCount": itemCount}).WithError(inventory.ErrInvalidItemId).Error("invalid item count for remove")
-		return sales.ErrInvalidItemCount
+		return nil, sales.ErrInvalidItemCount
 	}
 
 	bi.Count = bi.Count - itemCount
@@ -169,27 +208,186 @@ func (ss *salesService) RemoveItem(ctx context.Context, basketId uuid.UUID, item
 		delete(basket.Items, si.Id)
 	}
 
-	ss.basketRepo.SaveBasket(ctx, basket)
+	_, err = ss.basketRepo.SaveBasket(ctx, basket)
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).WithError(err).Error("failed to save basket")
+		return nil, err
+	}
 
 	log.WithFields(log.Fields{"basketId": basketId, "sale_item": si, "itemCount": itemCount}).Info("item removed/updated in basket")
-	return nil
+	return basket, nil
 }
 
-func (ss *salesService) CancelBasket(ctx context.Context, basketId uuid.UUID) (error) {
+// AttachCertificate attaches tax exemption certificate to the basket and recalculates taxes of items already in basket
+func (ss *salesService) AttachCertificate(ctx context.Context, basketId uuid.UUID, cert *models.ExemptionCertificate) (*models.Basket, error) {
 	if basketId == uuid.Nil {
 		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrInvalidBasketId).Error("missing basketId")
-		return sales.ErrInvalidBasketId
+		return nil, sales.ErrInvalidBasketId
 	}
+
+	err := ss.checkCertificate(ctx, cert)
+	if err != nil {
+		return nil, err
+	}
+
 	basket, err := ss.basketRepo.GetBasketByID(ctx, basketId)
 	if err != nil {
-		return err
+		log.WithFields(log.Fields{"basketId": basketId, "certificate": cert}).WithError(err).Error("failed to get basket")
+		return nil, err
 	}
 	if basket == nil {
-		return sales.ErrInvalidBasketId
+		log.WithFields(log.Fields{"basketId": basketId, "certificate": cert}).WithError(sales.ErrInvalidBasketId).Error("failed to find basket with given id")
+		return nil, sales.ErrInvalidBasketId
 	}
 
 	if basket.State != models.BasketStateOpened {
-		return sales.ErrBasketNotOpen
+		log.WithFields(log.Fields{"basketId": basketId, "certificate": cert}).WithError(sales.ErrBasketNotOpen).Error("basket is not available")
+		return nil, sales.ErrBasketNotOpen
+	}
+
+	err = checkVersion(ctx, basket)
+	if err != nil {
+		return nil, err
+	}
+
+	basket.Certificate = cert
+
+	for id, bi := range basket.Items {
+		si, err := ss.taxService.GetSaleItem(ctx, bi.InventoryItem, basket.TaxScope())
+		if err != nil {
+			log.WithFields(log.Fields{"basketId": basketId, "item": bi.InventoryItem}).WithError(err).Error("failed to get sale item")
+			return nil, err
+		}
+		basket.Items[id] = &models.BasketItem{SaleItem: si, Count: bi.Count, PricedAt: bi.PricedAt}
+	}
+
+	_, err = ss.basketRepo.SaveBasket(ctx, basket)
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId, "certificate": cert}).WithError(err).Error("failed to save basket")
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{"basketId": basketId, "certificate": cert}).Info("exemption certificate attached to basket")
+	return basket, nil
+}
+
+// ApplyCoupon applies coupon with given code to the basket, discount of the coupon is applied when basket closed
+func (ss *salesService) ApplyCoupon(ctx context.Context, basketId uuid.UUID, code string) (*models.Basket, error) {
+	if basketId == uuid.Nil {
+		log.WithFields(log.Fields{"basketId": basketId, "code": code}).WithError(sales.ErrInvalidBasketId).Error("missing basketId")
+		return nil, sales.ErrInvalidBasketId
+	}
+
+	code = models.NormalizeCouponCode(code)
+	if code == "" {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrCouponNotFound).Error("missing coupon code")
+		return nil, sales.ErrCouponNotFound
+	}
+
+	basket, err := ss.basketRepo.GetBasketByID(ctx, basketId)
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId, "code": code}).WithError(err).Error("failed to get basket")
+		return nil, err
+	}
+	if basket == nil {
+		log.WithFields(log.Fields{"basketId": basketId, "code": code}).WithError(sales.ErrInvalidBasketId).Error("failed to find basket with given id")
+		return nil, sales.ErrInvalidBasketId
+	}
+
+	if basket.State != models.BasketStateOpened {
+		log.WithFields(log.Fields{"basketId": basketId, "code": code}).WithError(sales.ErrBasketNotOpen).Error("basket is not available")
+		return nil, sales.ErrBasketNotOpen
+	}
+
+	err = checkVersion(ctx, basket)
+	if err != nil {
+		return nil, err
+	}
+
+	if basket.HasCoupon(code) {
+		log.WithFields(log.Fields{"basketId": basketId, "code": code}).WithError(sales.ErrCouponAlreadyApplied).Error("coupon already applied")
+		return nil, sales.ErrCouponAlreadyApplied
+	}
+
+	_, err = ss.checkCoupon(ctx, code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	basket.Coupons = append(basket.Coupons, code)
+
+	_, err = ss.basketRepo.SaveBasket(ctx, basket)
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId, "code": code}).WithError(err).Error("failed to save basket")
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{"basketId": basketId, "code": code}).Info("coupon applied to basket")
+	return basket, nil
+}
+
+// Reprice calculates prices and taxes of all items in the basket again with current inventory prices and taxes
+func (ss *salesService) Reprice(ctx context.Context, basketId uuid.UUID) (*models.Basket, error) {
+	if basketId == uuid.Nil {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrInvalidBasketId).Error("missing basketId")
+		return nil, sales.ErrInvalidBasketId
+	}
+
+	basket, err := ss.basketRepo.GetBasketByID(ctx, basketId)
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(err).Error("failed to get basket")
+		return nil, err
+	}
+	if basket == nil {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrInvalidBasketId).Error("failed to find basket with given id")
+		return nil, sales.ErrInvalidBasketId
+	}
+
+	if basket.State != models.BasketStateOpened {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrBasketNotOpen).Error("basket is not available")
+		return nil, sales.ErrBasketNotOpen
+	}
+
+	err = checkVersion(ctx, basket)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ss.repriceItems(ctx, basket, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ss.basketRepo.SaveBasket(ctx, basket)
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(err).Error("failed to save basket")
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{"basketId": basketId}).Info("basket repriced")
+	return basket, nil
+}
+
+func (ss *salesService) CancelBasket(ctx context.Context, basketId uuid.UUID) (*models.Basket, error) {
+	if basketId == uuid.Nil {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrInvalidBasketId).Error("missing basketId")
+		return nil, sales.ErrInvalidBasketId
+	}
+	basket, err := ss.basketRepo.GetBasketByID(ctx, basketId)
+	if err != nil {
+		return nil, err
+	}
+	if basket == nil {
+		return nil, sales.ErrInvalidBasketId
+	}
+
+	if basket.State != models.BasketStateOpened {
+		return nil, sales.ErrBasketNotOpen
+	}
+
+	err = checkVersion(ctx, basket)
+	if err != nil {
+		return nil, err
 	}
 
 	basket.State = models.BasketStateCancelled
@@ -197,10 +395,10 @@ func (ss *salesService) CancelBasket(ctx context.Context, basketId uuid.UUID) (e
 	_, err = ss.basketRepo.SaveBasket(ctx, basket)
 	if err != nil {
 		log.WithFields(log.Fields{"basketId": basketId}).WithError(err).Error("failed to cancel basket")
-		return err
+		return nil, err
 	}
 	log.WithFields(log.Fields{"basketId": basketId}).Info("basket cancelled")
-	return nil
+	return basket, nil
 }
 
 func (ss *salesService) CloseBasket(ctx context.Context, basketId uuid.UUID) (*models.Receipt, error) {
@@ -223,43 +421,124 @@ func (ss *salesService) CloseBasket(ctx context.Context, basketId uuid.UUID) (*m
 		return nil, sales.ErrBasketNotOpen
 	}
 
+	err = checkVersion(ctx, basket)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(basket.Items) == 0 {
 		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrNotItemInBasket).Error("basket is empty")
 		return nil, sales.ErrNotItemInBasket
 	}
 
-	items := make([]*models.BasketItem, 0, len(basket.Items))
+	closedAt := time.Now()
 
-	totalTax := decimal.Zero
-	totalPrice := decimal.Zero
-	totalGross := decimal.Zero
+	// certificate checked again, basket taxes are exempt as of basket creation but it may expire while basket is open
+	if basket.Certificate != nil && !closedAt.Before(basket.Certificate.ExpiresAt) {
+		log.WithFields(log.Fields{"basketId": basketId, "certificate": basket.Certificate}).WithError(sales.ErrCertificateExpired).Error("certificate expired before basket closed")
+		return nil, sales.ErrCertificateExpired
+	}
+
+	if ss.repricing == models.RepricingAtClose {
+		err = ss.repriceItems(ctx, basket, closedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	items := make([]*models.BasketItem, 0, len(basket.Items))
 	for _, v := range basket.Items {
 		items = append(items, v)
-		totalTax = totalTax.Add(v.TotalTax())
-		totalPrice = totalPrice.Add(v.TotalPrice())
-		totalGross = totalGross.Add(v.TotalGross())
 	}
 
-	receipt := &models.Receipt{
-		Id:         uuid.NewV1(),
-		Items:      items,
-		TotalTax:   totalTax,
-		TotalPrice: totalPrice,
-		TotalGross: totalGross,
+	// coupons checked again, they may expire or reach their limits while basket is open
+	coupons := make([]*models.Coupon, 0, len(basket.Coupons))
+	for _, code := range basket.Coupons {
+		coupon, err := ss.checkCoupon(ctx, code, closedAt)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
 	}
 
-	receipt, err = ss.receiptRepo.SaveReceipt(ctx, receipt)
+	discounts, err := ss.discountRepo.FetchAllDiscounts(ctx)
 	if err != nil {
-		log.WithFields(log.Fields{"basketId": basketId, "receipt": receipt}).WithError(err).Error("failed to save receipt")
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(err).Error("failed to fetch discounts")
 		return nil, err
 	}
+	discounts = basketDiscounts(discounts, coupons)
+
+	discountLines, perItem := applyDiscounts(items, discounts)
+	totalDiscount := sumDiscounts(discountLines)
+
+	// items keep listed prices on receipt, taxes calculated from discounted items when policy requires
+	taxed := items
+	if ss.policy == models.DiscountTaxAfterDiscount && totalDiscount.IsPositive() {
+		taxed, err = ss.discountedItems(ctx, basket.TaxScope(), items, perItem)
+		if err != nil {
+			log.WithFields(log.Fields{"basketId": basketId}).WithError(err).Error("failed to calculate taxes of discounted items")
+			return nil, err
+		}
+	}
+
+	breakdown, totalTax, totalPrice, totalGross := summarize(taxed)
+	if ss.policy != models.DiscountTaxAfterDiscount {
+		// discounts are on shelf prices, they reduce the amount paid while taxes stay as calculated on listed prices
+		totalGross = totalGross.Sub(totalDiscount)
+		totalPrice = totalGross.Sub(totalTax)
+	}
+
+	receipt := &models.Receipt{
+		Id:            uuid.NewV1(),
+		BasketId:      basket.Id,
+		ZoneId:        basket.ZoneId,
+		IssuedAt:      closedAt,
+		Items:         items,
+		TaxBreakdown:  breakdown,
+		TotalTax:      totalTax,
+		TotalPrice:    totalPrice,
+		TotalGross:    totalGross,
+		Certificate:   basket.Certificate,
+		Discounts:     discountLines,
+		Coupons:       basket.Coupons,
+		TotalDiscount: totalDiscount,
+		TaxPolicy:     ss.policy,
+	}
 
 	basket.State = models.BasketStateClosed
-	_, err = ss.basketRepo.SaveBasket(ctx, basket)
+
+	// coupon usages, receipt and basket state committed together, so a basket can't be left open with a saved receipt
+	err = ss.transactor.RunInTransaction(ctx, func(ctx context.Context) error {
+		// coupons read again in the transaction, so concurrently closed baskets can't use them over their limits
+		for _, code := range basket.Coupons {
+			coupon, err := ss.checkCoupon(ctx, code, closedAt)
+			if err != nil {
+				return err
+			}
+
+			coupon.Uses++
+			_, err = ss.couponRepo.SaveCoupon(ctx, coupon)
+			if err != nil {
+				log.WithFields(log.Fields{"basketId": basketId, "coupon": coupon}).WithError(err).Error("failed to update coupon usage")
+				return err
+			}
+		}
+
+		_, err := ss.receiptRepo.SaveReceipt(ctx, receipt)
+		if err != nil {
+			log.WithFields(log.Fields{"basketId": basketId, "receipt": receipt}).WithError(err).Error("failed to save receipt")
+			return err
+		}
+
+		_, err = ss.basketRepo.SaveBasket(ctx, basket)
+		if err != nil {
+			log.WithFields(log.Fields{"basketId": basketId, "receipt": receipt}).WithError(err).Error("failed to close basket")
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		log.WithFields(log.Fields{"basketId": basketId, "receipt": receipt}).WithError(err).Error("failed to close basket")
-		return receipt, err
+		return nil, err
 	}
 
 	log.WithFields(log.Fields{"basketId": basketId, "receipt": receipt}).Info("basket closed")
@@ -275,6 +554,452 @@ func (ss *salesService) GetReceiptByID(ctx context.Context, receiptId uuid.UUID)
 	return ss.receiptRepo.GetReceiptByID(ctx, receiptId)
 }
 
+// GetReceiptByBasketID returns receipt of the basket, basket must be closed
+func (ss *salesService) GetReceiptByBasketID(ctx context.Context, basketId uuid.UUID) (*models.Receipt, error) {
+	if basketId == uuid.Nil {
+		log.WithError(sales.ErrInvalidBasketId).Error("missing basketId")
+		return nil, sales.ErrInvalidBasketId
+	}
+
+	basket, err := ss.basketRepo.GetBasketByID(ctx, basketId)
+	if err != nil {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(err).Error("failed to get basket")
+		return nil, err
+	}
+	if basket == nil {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrInvalidBasketId).Error("failed to find basket with given id")
+		return nil, sales.ErrInvalidBasketId
+	}
+
+	if basket.State != models.BasketStateClosed {
+		log.WithFields(log.Fields{"basketId": basketId}).WithError(sales.ErrBasketNotClosed).Error("basket has no receipt")
+		return nil, sales.ErrBasketNotClosed
+	}
+
+	return ss.receiptRepo.GetReceiptByBasketID(ctx, basketId)
+}
+
 func (ss *salesService) FetchAllReceipts(ctx context.Context) ([]*models.Receipt, error) {
 	return ss.receiptRepo.FetchAllReceipts(ctx)
 }
+
+// FetchReceiptsIssuedBetween returns receipts issued in [from, to), zero from or to means the range is not bounded
+// on that side
+func (ss *salesService) FetchReceiptsIssuedBetween(ctx context.Context, from, to time.Time) ([]*models.Receipt, error) {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		log.WithFields(log.Fields{"from": from, "to": to}).WithError(sales.ErrInvalidReceiptRange).Error("invalid receipt date range")
+		return nil, sales.ErrInvalidReceiptRange
+	}
+
+	return ss.receiptRepo.FetchReceiptsIssuedBetween(ctx, from, to)
+}
+
+// Quote calculates prices and taxes of given items in given zone without creating a basket
+func (ss *salesService) Quote(ctx context.Context, zoneId uuid.UUID, lines []*models.QuoteLine) (*models.Quote, error) {
+	if len(lines) == 0 {
+		log.WithError(sales.ErrNotItemInBasket).Error("missing quote items")
+		return nil, sales.ErrNotItemInBasket
+	}
+
+	err := ss.checkZone(ctx, zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := models.TaxScope{At: time.Now(), ZoneId: zoneId}
+
+	items := make([]*models.BasketItem, 0, len(lines))
+
+	for _, line := range lines {
+		if line == nil {
+			log.WithError(sales.ErrInvalidParameter).Error("missing quote line")
+			return nil, sales.ErrInvalidParameter
+		}
+		if line.Count <= 0 {
+			log.WithFields(log.Fields{"line": line}).WithError(sales.ErrInvalidItemCount).Error("invalid item count")
+			return nil, sales.ErrInvalidItemCount
+		}
+
+		item := line.Item
+
+		if line.ItemId != uuid.Nil {
+			item, err = ss.invService.GetItemByID(ctx, line.ItemId)
+			if err != nil {
+				log.WithFields(log.Fields{"line": line}).WithError(err).Error("failed to get item with given id")
+				return nil, err
+			}
+			if item == nil {
+				log.WithFields(log.Fields{"line": line}).WithError(inventory.ErrInvalidItemId).Error("failed to find item with given id")
+				return nil, inventory.ErrInvalidItemId
+			}
+		}
+
+		if item == nil {
+			log.WithFields(log.Fields{"line": line}).WithError(inventory.ErrInvalidItemId).Error("missing item id or item")
+			return nil, inventory.ErrInvalidItemId
+		}
+		if item.Price.IsNegative() {
+			log.WithFields(log.Fields{"line": line}).WithError(inventory.ErrInvalidItemPrice).Error("invalid item price")
+			return nil, inventory.ErrInvalidItemPrice
+		}
+		if !item.Origin.IsValid() {
+			log.WithFields(log.Fields{"line": line}).WithError(inventory.ErrInvalidItemOrigin).Error("invalid item origin")
+			return nil, inventory.ErrInvalidItemOrigin
+		}
+
+		si, err := ss.taxService.GetSaleItem(ctx, item, scope)
+		if err != nil {
+			log.WithFields(log.Fields{"line": line}).WithError(err).Error("failed to get sale item")
+			return nil, err
+		}
+
+		items = append(items, &models.BasketItem{SaleItem: si, Count: line.Count})
+	}
+
+	breakdown, totalTax, totalPrice, totalGross := summarize(items)
+
+	return &models.Quote{
+		ZoneId:       zoneId,
+		At:           scope.At,
+		Items:        items,
+		TaxBreakdown: breakdown,
+		TotalTax:     totalTax,
+		TotalPrice:   totalPrice,
+		TotalGross:   totalGross,
+	}, nil
+}
+
+// CreateDiscount validates and saves a discount, saved discounts applied to all baskets when they are closed unless
+// they are coupon only
+func (ss *salesService) CreateDiscount(ctx context.Context, discount *models.Discount) (*models.Discount, error) {
+	if discount == nil {
+		log.WithError(sales.ErrInvalidParameter).Error("missing discount")
+		return nil, sales.ErrInvalidParameter
+	}
+	if strings.TrimSpace(discount.Name) == "" {
+		log.WithFields(log.Fields{"discount": discount}).WithError(sales.ErrInvalidDiscountName).Error("missing discount name")
+		return nil, sales.ErrInvalidDiscountName
+	}
+
+	err := ss.checkDiscount(ctx, discount)
+	if err != nil {
+		return nil, err
+	}
+
+	if discount.Id != uuid.Nil {
+		exist, err := ss.discountRepo.GetDiscountByID(ctx, discount.Id)
+		if err != nil {
+			log.WithFields(log.Fields{"discount": discount}).WithError(err).Error("failed to check existing discount with given id")
+			return nil, err
+		}
+		if exist != nil {
+			log.WithFields(log.Fields{"discount": discount}).WithError(sales.ErrInvalidDiscountId).Error("discount exist with given id")
+			return nil, sales.ErrInvalidDiscountId
+		}
+	} else {
+		discount.Id = uuid.NewV1()
+	}
+
+	nd, err := ss.discountRepo.SaveDiscount(ctx, discount)
+	if err != nil {
+		log.WithFields(log.Fields{"discount": discount}).WithError(err).Error("failed to create discount")
+		return nil, err
+	}
+	log.WithFields(log.Fields{"discount": discount}).Info("discount created")
+	return nd, nil
+}
+
+func (ss *salesService) GetDiscountByID(ctx context.Context, discountId uuid.UUID) (*models.Discount, error) {
+	if discountId == uuid.Nil {
+		log.WithError(sales.ErrInvalidDiscountId).Error("missing discount id")
+		return nil, sales.ErrInvalidDiscountId
+	}
+
+	return ss.discountRepo.GetDiscountByID(ctx, discountId)
+}
+
+func (ss *salesService) FetchAllDiscounts(ctx context.Context) ([]*models.Discount, error) {
+	return ss.discountRepo.FetchAllDiscounts(ctx)
+}
+
+func (ss *salesService) DeleteDiscount(ctx context.Context, discountId uuid.UUID) (*models.Discount, error) {
+	if discountId == uuid.Nil {
+		log.WithError(sales.ErrInvalidDiscountId).Error("missing discount id")
+		return nil, sales.ErrInvalidDiscountId
+	}
+
+	deleted, err := ss.discountRepo.DeleteDiscount(ctx, discountId)
+	if err != nil {
+		log.WithFields(log.Fields{"discountId": discountId}).WithError(err).Error("failed to delete discount")
+		return nil, err
+	}
+	if deleted == nil {
+		log.WithFields(log.Fields{"discountId": discountId}).WithError(sales.ErrInvalidDiscountId).Error("failed to find discount with given id")
+		return nil, sales.ErrInvalidDiscountId
+	}
+	log.WithFields(log.Fields{"discountId": discountId}).Info("discount deleted")
+	return deleted, nil
+}
+
+// CreateCoupon validates and saves a coupon for a coupon only discount
+func (ss *salesService) CreateCoupon(ctx context.Context, coupon *models.Coupon) (*models.Coupon, error) {
+	if coupon == nil {
+		log.WithError(sales.ErrInvalidParameter).Error("missing coupon")
+		return nil, sales.ErrInvalidParameter
+	}
+
+	coupon.Code = models.NormalizeCouponCode(coupon.Code)
+	if coupon.Code == "" {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(sales.ErrInvalidCouponCode).Error("missing coupon code")
+		return nil, sales.ErrInvalidCouponCode
+	}
+	if coupon.MaxUses < 0 {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(sales.ErrInvalidCouponLimit).Error("invalid coupon usage limit")
+		return nil, sales.ErrInvalidCouponLimit
+	}
+
+	exist, err := ss.couponRepo.GetCouponByCode(ctx, coupon.Code)
+	if err != nil {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(err).Error("failed to check existing coupon with given code")
+		return nil, err
+	}
+	if exist != nil {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(sales.ErrInvalidCouponCode).Error("coupon exist with given code")
+		return nil, sales.ErrInvalidCouponCode
+	}
+
+	discount, err := ss.discountRepo.GetDiscountByID(ctx, coupon.DiscountId)
+	if err != nil {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(err).Error("failed to get discount of coupon")
+		return nil, err
+	}
+	if discount == nil || !discount.CouponOnly {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(sales.ErrInvalidCouponDiscount).Error("invalid discount of coupon")
+		return nil, sales.ErrInvalidCouponDiscount
+	}
+
+	coupon.Uses = 0
+
+	nc, err := ss.couponRepo.SaveCoupon(ctx, coupon)
+	if err != nil {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(err).Error("failed to create coupon")
+		return nil, err
+	}
+	log.WithFields(log.Fields{"coupon": coupon}).Info("coupon created")
+	return nc, nil
+}
+
+func (ss *salesService) GetCouponByCode(ctx context.Context, code string) (*models.Coupon, error) {
+	code = models.NormalizeCouponCode(code)
+	if code == "" {
+		log.WithError(sales.ErrInvalidCouponCode).Error("missing coupon code")
+		return nil, sales.ErrInvalidCouponCode
+	}
+
+	return ss.couponRepo.GetCouponByCode(ctx, code)
+}
+
+func (ss *salesService) FetchAllCoupons(ctx context.Context) ([]*models.Coupon, error) {
+	return ss.couponRepo.FetchAllCoupons(ctx)
+}
+
+func (ss *salesService) DeleteCoupon(ctx context.Context, code string) (*models.Coupon, error) {
+	code = models.NormalizeCouponCode(code)
+	if code == "" {
+		log.WithError(sales.ErrInvalidCouponCode).Error("missing coupon code")
+		return nil, sales.ErrInvalidCouponCode
+	}
+
+	deleted, err := ss.couponRepo.DeleteCoupon(ctx, code)
+	if err != nil {
+		log.WithFields(log.Fields{"code": code}).WithError(err).Error("failed to delete coupon")
+		return nil, err
+	}
+	if deleted == nil {
+		log.WithFields(log.Fields{"code": code}).WithError(sales.ErrCouponNotFound).Error("failed to find coupon with given code")
+		return nil, sales.ErrCouponNotFound
+	}
+	log.WithFields(log.Fields{"code": code}).Info("coupon deleted")
+	return deleted, nil
+}
+
+// checkCoupon returns coupon with given code if it can be used at given time
+func (ss *salesService) checkCoupon(ctx context.Context, code string, at time.Time) (*models.Coupon, error) {
+	coupon, err := ss.couponRepo.GetCouponByCode(ctx, code)
+	if err != nil {
+		log.WithFields(log.Fields{"code": code}).WithError(err).Error("failed to get coupon")
+		return nil, err
+	}
+	if coupon == nil {
+		log.WithFields(log.Fields{"code": code}).WithError(sales.ErrCouponNotFound).Error("failed to find coupon with given code")
+		return nil, sales.ErrCouponNotFound
+	}
+
+	if coupon.IsExpiredAt(at) {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(sales.ErrCouponExpired).Error("coupon expired")
+		return nil, sales.ErrCouponExpired
+	}
+	if coupon.IsUsedUp() {
+		log.WithFields(log.Fields{"coupon": coupon}).WithError(sales.ErrCouponUsedUp).Error("coupon used up")
+		return nil, sales.ErrCouponUsedUp
+	}
+	return coupon, nil
+}
+
+// checkDiscount validates amount of the discount and item or category it applies to
+func (ss *salesService) checkDiscount(ctx context.Context, discount *models.Discount) error {
+	switch discount.Scope {
+	case models.DiscountScopeItem, models.DiscountScopeBuyXGetY:
+		item, err := ss.invService.GetItemByID(ctx, discount.ItemId)
+		if err != nil {
+			log.WithFields(log.Fields{"discount": discount}).WithError(err).Error("failed to get item of discount")
+			return err
+		}
+		if item == nil {
+			log.WithFields(log.Fields{"discount": discount}).WithError(inventory.ErrInvalidItemId).Error("failed to find item of discount")
+			return inventory.ErrInvalidItemId
+		}
+	case models.DiscountScopeCategory:
+		cat, err := ss.invService.GetCategoryByID(ctx, discount.CategoryId)
+		if err != nil {
+			log.WithFields(log.Fields{"discount": discount}).WithError(err).Error("failed to get category of discount")
+			return err
+		}
+		if cat == nil {
+			log.WithFields(log.Fields{"discount": discount}).WithError(inventory.ErrInvalidCategoryId).Error("failed to find category of discount")
+			return inventory.ErrInvalidCategoryId
+		}
+	case models.DiscountScopeBasket:
+	default:
+		log.WithFields(log.Fields{"discount": discount}).WithError(sales.ErrInvalidDiscountScope).Error("unknown discount scope")
+		return sales.ErrInvalidDiscountScope
+	}
+
+	if discount.Scope == models.DiscountScopeBuyXGetY {
+		if discount.Buy <= 0 || discount.Get <= 0 {
+			log.WithFields(log.Fields{"discount": discount}).WithError(sales.ErrInvalidDiscountQuantity).Error("invalid buy or get quantity")
+			return sales.ErrInvalidDiscountQuantity
+		}
+		return nil
+	}
+
+	switch discount.Kind {
+	case models.DiscountKindPercentage:
+		if !discount.Rate.IsPositive() || discount.Rate.GreaterThan(hundred) {
+			log.WithFields(log.Fields{"discount": discount}).WithError(sales.ErrInvalidDiscountRate).Error("invalid discount rate")
+			return sales.ErrInvalidDiscountRate
+		}
+	case models.DiscountKindFixed:
+		if !discount.Amount.IsPositive() {
+			log.WithFields(log.Fields{"discount": discount}).WithError(sales.ErrInvalidDiscountAmount).Error("invalid discount amount")
+			return sales.ErrInvalidDiscountAmount
+		}
+	default:
+		log.WithFields(log.Fields{"discount": discount}).WithError(sales.ErrInvalidDiscountKind).Error("unknown discount kind")
+		return sales.ErrInvalidDiscountKind
+	}
+	return nil
+}
+
+// repriceItems replaces items of the basket with current inventory items priced with taxes in force at given time
+func (ss *salesService) repriceItems(ctx context.Context, basket *models.Basket, at time.Time) error {
+	basket.PricedAt = at
+
+	for id, bi := range basket.Items {
+		item, err := ss.invService.GetItemByID(ctx, id)
+		if err != nil {
+			log.WithFields(log.Fields{"basketId": basket.Id, "itemId": id}).WithError(err).Error("failed to get item with given id")
+			return err
+		}
+		if item == nil {
+			log.WithFields(log.Fields{"basketId": basket.Id, "itemId": id}).WithError(inventory.ErrInvalidItemId).Error("item in basket no longer exists")
+			return inventory.ErrInvalidItemId
+		}
+
+		si, err := ss.taxService.GetSaleItem(ctx, item, basket.TaxScope())
+		if err != nil {
+			log.WithFields(log.Fields{"basketId": basket.Id, "item": item}).WithError(err).Error("failed to get sale item")
+			return err
+		}
+		basket.Items[id] = &models.BasketItem{SaleItem: si, Count: bi.Count, PricedAt: at}
+	}
+	return nil
+}
+
+// checkCertificate validates certificate is not expired and covers existing taxes
+func (ss *salesService) checkCertificate(ctx context.Context, cert *models.ExemptionCertificate) error {
+	if cert == nil || strings.TrimSpace(cert.Id) == "" || len(cert.TaxIds) == 0 {
+		log.WithFields(log.Fields{"certificate": cert}).WithError(sales.ErrInvalidCertificate).Error("missing certificate id or taxes")
+		return sales.ErrInvalidCertificate
+	}
+
+	if !time.Now().Before(cert.ExpiresAt) {
+		log.WithFields(log.Fields{"certificate": cert}).WithError(sales.ErrCertificateExpired).Error("certificate expired")
+		return sales.ErrCertificateExpired
+	}
+
+	for _, taxId := range cert.TaxIds {
+		tax, err := ss.taxService.GetTaxByID(ctx, taxId)
+		if err != nil {
+			log.WithFields(log.Fields{"certificate": cert, "taxId": taxId}).WithError(err).Error("failed to get tax")
+			return err
+		}
+		if tax == nil {
+			log.WithFields(log.Fields{"certificate": cert, "taxId": taxId}).WithError(sales.ErrInvalidCertificate).Error("certificate covers unknown tax")
+			return sales.ErrInvalidCertificate
+		}
+	}
+	return nil
+}
+
+// checkVersion checks basket is not changed since the version caller expects, callers without a version always match
+func checkVersion(ctx context.Context, basket *models.Basket) error {
+	version, ok := sales.BasketVersionFromContext(ctx)
+	if ok && version != basket.Version {
+		log.WithFields(log.Fields{"basketId": basket.Id, "version": version, "current": basket.Version}).WithError(sales.ErrBasketConflict).Error("basket changed since expected version")
+		return sales.ErrBasketConflict
+	}
+	return nil
+}
+
+// checkZone checks zone exists, nil zone is always valid
+func (ss *salesService) checkZone(ctx context.Context, zoneId uuid.UUID) error {
+	if zoneId == uuid.Nil {
+		return nil
+	}
+
+	zone, err := ss.taxService.GetZoneByID(ctx, zoneId)
+	if err != nil {
+		log.WithFields(log.Fields{"zoneId": zoneId}).WithError(err).Error("failed to get zone")
+		return err
+	}
+	if zone == nil {
+		log.WithFields(log.Fields{"zoneId": zoneId}).WithError(taxes.ErrInvalidZoneId).Error("failed to find zone with given id")
+		return taxes.ErrInvalidZoneId
+	}
+	return nil
+}
+
+// summarize calculates totals of items and aggregates amounts of same tax across items
+func summarize(items []*models.BasketItem) (breakdown []*models.TaxLine, totalTax, totalPrice, totalGross decimal.Decimal) {
+	breakdown = make([]*models.TaxLine, 0)
+	taxLines := make(map[uuid.UUID]*models.TaxLine)
+
+	for _, v := range items {
+		totalTax = totalTax.Add(v.TotalTax())
+		totalPrice = totalPrice.Add(v.TotalPrice())
+		totalGross = totalGross.Add(v.TotalGross())
+
+		for _, tl := range v.TotalBreakdown() {
+			if existing, ok := taxLines[tl.TaxId]; ok {
+				existing.Base = existing.Base.Add(tl.Base)
+				existing.Amount = existing.Amount.Add(tl.Amount)
+				continue
+			}
+			taxLines[tl.TaxId] = tl
+			breakdown = append(breakdown, tl)
+		}
+	}
+
+	return breakdown, totalTax, totalPrice, totalGross
+}
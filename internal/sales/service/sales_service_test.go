@@ -11,7 +11,9 @@ import (
 	"github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
+	"time"
 	salesRepository "github.com/aweris/stp/internal/sales/repository"
 	salesService "github.com/aweris/stp/internal/sales/service"
 	taxRepository "github.com/aweris/stp/internal/taxes/repository"
@@ -32,19 +34,31 @@ type mockedService struct {
 }
 
 func newMockedService() *mockedService {
+	return newMockedServiceWithPolicy(models.DiscountTaxAfterDiscount)
+}
+
+func newMockedServiceWithPolicy(policy models.DiscountTaxPolicy) *mockedService {
+	return newMockedServiceWithPolicies(policy, models.RepricingLockAtAdd)
+}
+
+func newMockedServiceWithPolicies(policy models.DiscountTaxPolicy, repricing models.RepricingPolicy) *mockedService {
 	db := storage.NewTestDB()
 
 	cr := inventoryRepository.NewBoltDBCategoryRepository(db.BoltDB)
 	ir := inventoryRepository.NewBoltDBItemRepository(db.BoltDB)
-	is := inventoryService.NewInventoryService(ir, cr)
-
 	tr := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
-	ts := taxService.NewTaxService(tr)
+	thr := taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+	zr := taxRepository.NewBoltDBZoneRepository(db.BoltDB)
+
+	ts := taxService.NewTaxService(tr, thr, zr, cr, db.BoltDB, models.TaxCalculationPerTax)
+	is := inventoryService.NewInventoryService(ir, cr, ts, db.BoltDB)
 
 	br := salesRepository.NewBoltDBBasketRepository(db.BoltDB)
 	rr := salesRepository.NewBoltDBReceiptRepository(db.BoltDB)
+	dr := salesRepository.NewBoltDBDiscountRepository(db.BoltDB)
+	cpr := salesRepository.NewBoltDBCouponRepository(db.BoltDB)
 
-	ss := salesService.NewSalesService(br, rr, is, ts)
+	ss := salesService.NewSalesService(br, rr, dr, cpr, db.BoltDB, is, ts, policy, repricing)
 
 	return &mockedService{db: db, SalesService: ss, br: br, rr: rr, is: is, ts: ts}
 }
@@ -57,7 +71,7 @@ func TestSalesService_CreateBasket(t *testing.T) {
 	ts := newMockedService()
 	defer ts.Close()
 
-	bid, err := ts.CreateBasket(context.Background())
+	bid, err := ts.CreateBasket(context.Background(), uuid.Nil)
 	assert.NoError(t, err)
 	assert.NotEqual(t, uuid.Nil, bid)
 }
@@ -66,7 +80,7 @@ func TestSalesService_GetBasketByID(t *testing.T) {
 	ts := newMockedService()
 	defer ts.Close()
 
-	bid, err := ts.CreateBasket(context.Background())
+	bid, err := ts.CreateBasket(context.Background(), uuid.Nil)
 	assert.NoError(t, err)
 	assert.NotEqual(t, uuid.Nil, bid)
 
@@ -113,10 +127,10 @@ func TestSalesService_AddItem_WhenBucketEmpty_ThenResultMustBeSuccess(t *testing
 	item, err = ts.is.CreateItem(ctx, item)
 	assert.NoError(t, err, "failed to add item")
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.AddItem(ctx, bid, item.Id, 1)
+	_, err = ts.AddItem(ctx, bid, item.Id, 1)
 	assert.NoError(t, err)
 }
 
@@ -142,10 +156,10 @@ func TestSalesService_AddItem_WhenBucketHadItemAlready_ThanShouldUpdateCount(t *
 	item, err = ts.is.CreateItem(ctx, item)
 	assert.NoError(t, err, "failed to add item")
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.AddItem(ctx, bid, item.Id, 1)
+	_, err = ts.AddItem(ctx, bid, item.Id, 1)
 	assert.NoError(t, err)
 
 	basket, err := ts.br.GetBasketByID(ctx, bid)
@@ -153,7 +167,7 @@ func TestSalesService_AddItem_WhenBucketHadItemAlready_ThanShouldUpdateCount(t *
 	assert.NotNil(t, basket.Items[item.Id])
 	assert.Equal(t, 1, basket.Items[item.Id].Count)
 
-	err = ts.AddItem(ctx, bid, item.Id, 5)
+	_, err = ts.AddItem(ctx, bid, item.Id, 5)
 	assert.NoError(t, err)
 
 	basket, err = ts.br.GetBasketByID(ctx, bid)
@@ -168,7 +182,7 @@ func TestSalesService_AddItem_WhenBasketIdIsNil_ThanShouldReturnErr(t *testing.T
 
 	ctx := context.Background()
 
-	err := ts.AddItem(ctx, uuid.Nil, uuid.NewV1(), 1)
+	_, err := ts.AddItem(ctx, uuid.Nil, uuid.NewV1(), 1)
 	assert.Equal(t, sales.ErrInvalidBasketId, err)
 }
 
@@ -178,7 +192,7 @@ func TestSalesService_AddItem_WhenItemIdIsNil_ThanShouldReturnErr(t *testing.T)
 
 	ctx := context.Background()
 
-	err := ts.AddItem(ctx, uuid.NewV1(), uuid.Nil, 1)
+	_, err := ts.AddItem(ctx, uuid.NewV1(), uuid.Nil, 1)
 	assert.Equal(t, inventory.ErrInvalidItemId, err)
 }
 
@@ -188,7 +202,7 @@ func TestSalesService_AddItem_WhenItemCountIsZero_ThanShouldReturnErr(t *testing
 
 	ctx := context.Background()
 
-	err := ts.AddItem(ctx, uuid.NewV1(), uuid.NewV1(), 0)
+	_, err := ts.AddItem(ctx, uuid.NewV1(), uuid.NewV1(), 0)
 	assert.Equal(t, sales.ErrInvalidItemCount, err)
 }
 
@@ -198,7 +212,7 @@ func TestSalesService_AddItem_WhenBasketIdNotExist_ThanShouldReturnErr(t *testin
 
 	ctx := context.Background()
 
-	err := ts.AddItem(ctx, uuid.NewV1(), uuid.NewV1(), 2)
+	_, err := ts.AddItem(ctx, uuid.NewV1(), uuid.NewV1(), 2)
 	assert.Equal(t, sales.ErrInvalidBasketId, err)
 }
 
@@ -224,10 +238,10 @@ func TestSalesService_RemoveItem_WhenItemCountBiggerThanRemove_ThanShouldRemoveI
 	item, err = ts.is.CreateItem(ctx, item)
 	assert.NoError(t, err, "failed to add item")
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.AddItem(ctx, bid, item.Id, 10)
+	_, err = ts.AddItem(ctx, bid, item.Id, 10)
 	assert.NoError(t, err)
 
 	basket, err := ts.br.GetBasketByID(ctx, bid)
@@ -235,7 +249,7 @@ func TestSalesService_RemoveItem_WhenItemCountBiggerThanRemove_ThanShouldRemoveI
 	assert.NotNil(t, basket.Items[item.Id])
 	assert.Equal(t, 10, basket.Items[item.Id].Count)
 
-	err = ts.RemoveItem(ctx, bid, item.Id, 8)
+	_, err = ts.RemoveItem(ctx, bid, item.Id, 8)
 	assert.NoError(t, err)
 
 	basket, err = ts.br.GetBasketByID(ctx, bid)
@@ -266,10 +280,10 @@ func TestSalesService_RemoveItem_WhenItemCountEqual_ThanShouldRemoveItemFromList
 	item, err = ts.is.CreateItem(ctx, item)
 	assert.NoError(t, err, "failed to add item")
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.AddItem(ctx, bid, item.Id, 10)
+	_, err = ts.AddItem(ctx, bid, item.Id, 10)
 	assert.NoError(t, err)
 
 	basket, err := ts.br.GetBasketByID(ctx, bid)
@@ -277,7 +291,7 @@ func TestSalesService_RemoveItem_WhenItemCountEqual_ThanShouldRemoveItemFromList
 	assert.NotNil(t, basket.Items[item.Id])
 	assert.Equal(t, 10, basket.Items[item.Id].Count)
 
-	err = ts.RemoveItem(ctx, bid, item.Id, 10)
+	_, err = ts.RemoveItem(ctx, bid, item.Id, 10)
 	assert.NoError(t, err)
 
 	basket, err = ts.br.GetBasketByID(ctx, bid)
@@ -307,10 +321,10 @@ func TestSalesService_RemoveItem_WhenItemCountBigger_ThanShouldReturnErr(t *test
 	item, err = ts.is.CreateItem(ctx, item)
 	assert.NoError(t, err, "failed to add item")
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.AddItem(ctx, bid, item.Id, 10)
+	_, err = ts.AddItem(ctx, bid, item.Id, 10)
 	assert.NoError(t, err)
 
 	basket, err := ts.br.GetBasketByID(ctx, bid)
@@ -318,7 +332,7 @@ func TestSalesService_RemoveItem_WhenItemCountBigger_ThanShouldReturnErr(t *test
 	assert.NotNil(t, basket.Items[item.Id])
 	assert.Equal(t, 10, basket.Items[item.Id].Count)
 
-	err = ts.RemoveItem(ctx, bid, item.Id, 18)
+	_, err = ts.RemoveItem(ctx, bid, item.Id, 18)
 	assert.Equal(t, err, sales.ErrInvalidItemCount)
 }
 
@@ -328,7 +342,7 @@ func TestSalesService_RemoveItem_WhenBasketIdIsNil_ThanShouldReturnErr(t *testin
 
 	ctx := context.Background()
 
-	err := ts.RemoveItem(ctx, uuid.Nil, uuid.NewV1(), 1)
+	_, err := ts.RemoveItem(ctx, uuid.Nil, uuid.NewV1(), 1)
 	assert.Equal(t, sales.ErrInvalidBasketId, err)
 }
 
@@ -338,7 +352,7 @@ func TestSalesService_RemoveItem_WhenItemIdIsNil_ThanShouldReturnErr(t *testing.
 
 	ctx := context.Background()
 
-	err := ts.RemoveItem(ctx, uuid.NewV1(), uuid.Nil, 1)
+	_, err := ts.RemoveItem(ctx, uuid.NewV1(), uuid.Nil, 1)
 	assert.Equal(t, inventory.ErrInvalidItemId, err)
 }
 
@@ -348,7 +362,7 @@ func TestSalesService_RemoveItem_WhenItemCountIsZero_ThanShouldReturnErr(t *test
 
 	ctx := context.Background()
 
-	err := ts.RemoveItem(ctx, uuid.NewV1(), uuid.NewV1(), 0)
+	_, err := ts.RemoveItem(ctx, uuid.NewV1(), uuid.NewV1(), 0)
 	assert.Equal(t, sales.ErrInvalidItemCount, err)
 }
 
@@ -358,7 +372,7 @@ func TestSalesService_RemoveItem_WhenBasketIdNotExist_ThanShouldReturnErr(t *tes
 
 	ctx := context.Background()
 
-	err := ts.RemoveItem(ctx, uuid.NewV1(), uuid.NewV1(), 2)
+	_, err := ts.RemoveItem(ctx, uuid.NewV1(), uuid.NewV1(), 2)
 	assert.Equal(t, sales.ErrInvalidBasketId, err)
 }
 
@@ -368,7 +382,7 @@ func TestSalesService_CancelBasket_WhenBasketIdIsNil_ThanShouldReturnErr(t *test
 
 	ctx := context.Background()
 
-	err := ts.CancelBasket(ctx, uuid.Nil)
+	_, err := ts.CancelBasket(ctx, uuid.Nil)
 	assert.Equal(t, sales.ErrInvalidBasketId, err)
 }
 
@@ -378,7 +392,7 @@ func TestSalesService_CancelBasket_WhenBasketIdNotExist_ThanShouldReturnErr(t *t
 
 	ctx := context.Background()
 
-	err := ts.CancelBasket(ctx, uuid.NewV1())
+	_, err := ts.CancelBasket(ctx, uuid.NewV1())
 	assert.Equal(t, sales.ErrInvalidBasketId, err)
 }
 
@@ -388,13 +402,13 @@ func TestSalesService_CancelBasket_WhenBasketAlreadyClosedOrCanceled_ThanShouldR
 
 	ctx := context.Background()
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.CancelBasket(ctx, bid)
+	_, err = ts.CancelBasket(ctx, bid)
 	assert.NoError(t, err)
 
-	err = ts.CancelBasket(ctx, bid)
+	_, err = ts.CancelBasket(ctx, bid)
 	assert.Equal(t, sales.ErrBasketNotOpen, err)
 }
 
@@ -404,10 +418,10 @@ func TestSalesService_CancelBasket(t *testing.T) {
 
 	ctx := context.Background()
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.CancelBasket(ctx, bid)
+	_, err = ts.CancelBasket(ctx, bid)
 	assert.NoError(t, err)
 }
 
@@ -417,7 +431,7 @@ func TestSalesService_CloseBasket_WhenBasketEmpty_ThanShouldReturnErr(t *testing
 
 	ctx := context.Background()
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
 	_, err = ts.CloseBasket(ctx, bid)
@@ -456,10 +470,10 @@ func TestSalesService_CloseBasket_WhenBasketHasItems_ThanShouldCloseBasketWithRe
 	item, err = ts.is.CreateItem(ctx, item)
 	assert.NoError(t, err, "failed to add item")
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.AddItem(ctx, bid, item.Id, 10)
+	_, err = ts.AddItem(ctx, bid, item.Id, 10)
 	assert.NoError(t, err)
 
 	basket, err := ts.br.GetBasketByID(ctx, bid)
@@ -511,10 +525,10 @@ func TestSalesService_GetReceiptByID(t *testing.T) {
 	item, err = ts.is.CreateItem(ctx, item)
 	assert.NoError(t, err, "failed to add item")
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.AddItem(ctx, bid, item.Id, 10)
+	_, err = ts.AddItem(ctx, bid, item.Id, 10)
 	assert.NoError(t, err)
 
 	basket, err := ts.br.GetBasketByID(ctx, bid)
@@ -571,10 +585,10 @@ func TestSalesService_FetchAllReceipts(t *testing.T) {
 	item, err = ts.is.CreateItem(ctx, item)
 	assert.NoError(t, err, "failed to add item")
 
-	bid, err := ts.CreateBasket(ctx)
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
 	assert.NoError(t, err)
 
-	err = ts.AddItem(ctx, bid, item.Id, 10)
+	_, err = ts.AddItem(ctx, bid, item.Id, 10)
 	assert.NoError(t, err)
 
 	basket, err := ts.br.GetBasketByID(ctx, bid)
@@ -587,4 +601,1831 @@ func TestSalesService_FetchAllReceipts(t *testing.T) {
 	list, err := ts.FetchAllReceipts(ctx)
 	assert.NoError(t, err)
 	assert.Equal(t,1, len(list))
-}
\ No newline at end of file
+}
+func TestSalesService_CloseBasket_WhenItemsHaveMultipleTaxes_ThenReceiptShouldHaveTaxBreakdown(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	bst := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	bst, err := ts.ts.CreateTax(ctx, bst)
+	assert.NoError(t, err)
+
+	it := &models.Tax{
+		Name:   "Import Duty",
+		Rate:   decimal.NewFromFloat32(5),
+		Origin: models.TaxOriginImport,
+	}
+	it, err = ts.ts.CreateTax(ctx, it)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	local := &models.InventoryItem{
+		Name:       "Local Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(10),
+	}
+	local, err = ts.is.CreateItem(ctx, local)
+	assert.NoError(t, err, "failed to add item")
+
+	imported := &models.InventoryItem{
+		Name:       "Imported Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginImported,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	imported, err = ts.is.CreateItem(ctx, imported)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, local.Id, 2)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, imported.Id, 1)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	find, err := ts.GetReceiptByID(ctx, receipt.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(find.TaxBreakdown))
+
+	amounts := make(map[uuid.UUID]decimal.Decimal)
+	for _, v := range find.TaxBreakdown {
+		amounts[v.TaxId] = v.Amount
+	}
+
+	assert.True(t, amounts[bst.Id].Equal(decimal.NewFromFloat32(4)))
+	assert.True(t, amounts[it.Id].Equal(decimal.NewFromFloat32(1)))
+	assert.True(t, find.TotalTax.Equal(decimal.NewFromFloat32(5)))
+}
+
+func TestSalesService_CloseBasket_WhenItemPriceIsTaxInclusive_ThenReceiptTotalsShouldBeConsistent(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Test Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	item := &models.InventoryItem{
+		Name:         "Test Item",
+		CategoryId:   c.Id,
+		Origin:       models.ItemOriginLocal,
+		Price:        decimal.NewFromFloat32(11),
+		TaxInclusive: true,
+	}
+	item, err = ts.is.CreateItem(ctx, item)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, item.Id, 2)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+	assert.True(t, receipt.TotalGross.Equal(decimal.NewFromFloat32(22)))
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(20)))
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(2)))
+}
+
+func TestSalesService_AddItem_WhenTaxNotValidYet_ThenShouldNotApplyTax(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	from := time.Now().Add(time.Hour)
+
+	tax := &models.Tax{
+		Name:      "Future Tax",
+		Rate:      decimal.NewFromFloat32(10),
+		Origin:    models.TaxOriginAll,
+		ValidFrom: &from,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	item := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(10),
+	}
+	item, err = ts.is.CreateItem(ctx, item)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, item.Id, 1)
+	assert.NoError(t, err)
+
+	basket, err := ts.br.GetBasketByID(ctx, bid)
+	assert.NoError(t, err)
+	assert.True(t, basket.Items[item.Id].Taxes.IsZero())
+}
+
+func TestSalesService_CloseBasket_WhenTaxDeletedAfterItemAdded_ThenReceiptShouldKeepAppliedTaxDefinition(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:     "Test Tax",
+		Rate:     decimal.NewFromFloat32(10),
+		Origin:   models.TaxOriginAll,
+		Rounding: &models.RoundingRule{Increment: decimal.NewFromFloat32(0.01), Mode: models.RoundingModeHalfEven},
+	}
+	tax, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	item := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(10),
+	}
+	item, err = ts.is.CreateItem(ctx, item)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, item.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.ts.DeleteTax(ctx, tax.Id)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	find, err := ts.GetReceiptByID(ctx, receipt.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(find.Items))
+	assert.Equal(t, 1, len(find.Items[0].Breakdown))
+
+	line := find.Items[0].Breakdown[0]
+	assert.Equal(t, tax.Id, line.TaxId)
+	assert.Equal(t, tax.Name, line.Name)
+	assert.True(t, line.Rate.Equal(tax.Rate))
+	assert.Equal(t, models.RoundingModeHalfEven, line.Rounding.Mode)
+	assert.True(t, line.Rounding.Increment.Equal(tax.Rounding.Increment))
+}
+
+func TestSalesService_CloseBasket_WhenItemHasFixedTax_ThenShouldChargeTaxPerUnit(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	deposit := &models.Tax{
+		Name:   "Bottle Deposit",
+		Kind:   models.TaxKindFixed,
+		Amount: decimal.NewFromFloat32(0.25),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, deposit)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Beverages",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	item := &models.InventoryItem{
+		Name:       "Water",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(1),
+	}
+	item, err = ts.is.CreateItem(ctx, item)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, item.Id, 6)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(1.5)))
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(6)))
+	assert.True(t, receipt.TotalGross.Equal(decimal.NewFromFloat32(7.5)))
+
+	assert.Equal(t, 1, len(receipt.TaxBreakdown))
+	assert.Equal(t, models.TaxKindFixed, receipt.TaxBreakdown[0].Kind)
+	assert.True(t, receipt.TaxBreakdown[0].Amount.Equal(decimal.NewFromFloat32(1.5)))
+}
+
+func TestSalesService_CreateBasket_WhenZoneNotExist_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	_, err := ts.CreateBasket(context.Background(), uuid.NewV1())
+	assert.Equal(t, taxes.ErrInvalidZoneId, err)
+}
+
+func TestSalesService_AddItem_WhenBasketHasZone_ThenShouldApplyZoneTaxes(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	zone, err := ts.ts.CreateZone(ctx, &models.Zone{Name: "Ontario"})
+	assert.NoError(t, err)
+
+	tax := &models.Tax{
+		Name:   "Provincial Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+		Zones:  []uuid.UUID{zone.Id},
+	}
+	_, err = ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c, err := ts.is.CreateCategory(ctx, &models.Category{Name: "Test Category"})
+	assert.NoError(t, err, "failed to add category")
+
+	item := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(10),
+	}
+	item, err = ts.is.CreateItem(ctx, item)
+	assert.NoError(t, err, "failed to add item")
+
+	inZone, err := ts.CreateBasket(ctx, zone.Id)
+	assert.NoError(t, err)
+	noZone, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	for _, bid := range []uuid.UUID{inZone, noZone} {
+		_, err = ts.AddItem(ctx, bid, item.Id, 1)
+		assert.NoError(t, err)
+	}
+
+	basket, err := ts.GetBasketByID(ctx, inZone)
+	assert.NoError(t, err)
+	assert.Equal(t, zone.Id, basket.ZoneId)
+	assert.True(t, basket.Items[item.Id].Taxes.Equal(decimal.NewFromFloat32(1)))
+
+	basket, err = ts.GetBasketByID(ctx, noZone)
+	assert.NoError(t, err)
+	assert.True(t, basket.Items[item.Id].Taxes.Equal(decimal.Zero))
+}
+
+func TestSalesService_Quote_ShouldCalculateTotalsWithoutBasket(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c, err := ts.is.CreateCategory(ctx, &models.Category{Name: "Test Category"})
+	assert.NoError(t, err, "failed to add category")
+
+	item := &models.InventoryItem{
+		Name:       "Music CD",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(14.99),
+	}
+	item, err = ts.is.CreateItem(ctx, item)
+	assert.NoError(t, err, "failed to add item")
+
+	lines := []*models.QuoteLine{
+		{ItemId: item.Id, Count: 2},
+		{Item: &models.InventoryItem{Name: "Ad-hoc Item", CategoryId: c.Id, Origin: models.ItemOriginLocal, Price: decimal.NewFromFloat32(10)}, Count: 1},
+	}
+
+	quote, err := ts.Quote(ctx, uuid.Nil, lines)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(quote.Items))
+	assert.True(t, quote.TotalTax.Equal(decimal.NewFromFloat32(4)))
+	assert.True(t, quote.TotalGross.Equal(decimal.NewFromFloat32(43.98)))
+	assert.Equal(t, 1, len(quote.TaxBreakdown))
+
+	receipts, err := ts.FetchAllReceipts(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, receipts)
+}
+
+func TestSalesService_Quote_WhenLineHasNoItem_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	_, err := ts.Quote(context.Background(), uuid.Nil, []*models.QuoteLine{{Count: 1}})
+	assert.Equal(t, inventory.ErrInvalidItemId, err)
+
+	_, err = ts.Quote(context.Background(), uuid.Nil, []*models.QuoteLine{{ItemId: uuid.NewV1(), Count: 1}})
+	assert.Equal(t, inventory.ErrInvalidItemId, err)
+
+	_, err = ts.Quote(context.Background(), uuid.Nil, []*models.QuoteLine{{ItemId: uuid.NewV1(), Count: 0}})
+	assert.Equal(t, sales.ErrInvalidItemCount, err)
+}
+
+func TestSalesService_AttachCertificate_ShouldSkipCoveredTaxesAndRecordCertificateOnReceipt(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	bst := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	bst, err := ts.ts.CreateTax(ctx, bst)
+	assert.NoError(t, err)
+
+	it := &models.Tax{
+		Name:   "Import Duty",
+		Rate:   decimal.NewFromFloat32(5),
+		Origin: models.TaxOriginImport,
+	}
+	it, err = ts.ts.CreateTax(ctx, it)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	imported := &models.InventoryItem{
+		Name:       "Imported Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginImported,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	imported, err = ts.is.CreateItem(ctx, imported)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, imported.Id, 1)
+	assert.NoError(t, err)
+
+	cert := &models.ExemptionCertificate{
+		Id:        "RES-001",
+		Holder:    "Reseller Inc.",
+		TaxIds:    []uuid.UUID{bst.Id},
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	_, err = ts.AttachCertificate(ctx, bid, cert)
+	assert.NoError(t, err)
+
+	// items added before and after attaching certificate must both be exempt
+	_, err = ts.AddItem(ctx, bid, imported.Id, 1)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	find, err := ts.GetReceiptByID(ctx, receipt.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(find.TaxBreakdown))
+	assert.Equal(t, it.Id, find.TaxBreakdown[0].TaxId)
+	assert.True(t, find.TotalTax.Equal(decimal.NewFromFloat32(2)))
+	assert.NotNil(t, find.Certificate)
+	assert.Equal(t, cert.Id, find.Certificate.Id)
+}
+
+func TestSalesService_AttachCertificate_WhenCertificateInvalid_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AttachCertificate(ctx, bid, &models.ExemptionCertificate{TaxIds: []uuid.UUID{tax.Id}, ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Equal(t, sales.ErrInvalidCertificate, err)
+
+	_, err = ts.AttachCertificate(ctx, bid, &models.ExemptionCertificate{Id: "RES-001", TaxIds: []uuid.UUID{uuid.NewV1()}, ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Equal(t, sales.ErrInvalidCertificate, err)
+
+	_, err = ts.AttachCertificate(ctx, bid, &models.ExemptionCertificate{Id: "RES-001", TaxIds: []uuid.UUID{tax.Id}, ExpiresAt: time.Now().Add(-time.Hour)})
+	assert.Equal(t, sales.ErrCertificateExpired, err)
+
+	_, err = ts.AttachCertificate(ctx, uuid.NewV1(), &models.ExemptionCertificate{Id: "RES-001", TaxIds: []uuid.UUID{tax.Id}, ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Equal(t, sales.ErrInvalidBasketId, err)
+}
+
+func TestSalesService_CloseBasket_WhenCertificateExpiredAfterAttached_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	cert := &models.ExemptionCertificate{
+		Id:        "RES-001",
+		Holder:    "Reseller Inc.",
+		TaxIds:    []uuid.UUID{tax.Id},
+		ExpiresAt: time.Now().Add(50 * time.Millisecond),
+	}
+	_, err = ts.AttachCertificate(ctx, bid, cert)
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = ts.CloseBasket(ctx, bid)
+	assert.Equal(t, sales.ErrCertificateExpired, err)
+
+	find, err := ts.GetBasketByID(ctx, bid)
+	assert.NoError(t, err)
+	assert.Equal(t, models.BasketStateOpened, find.State)
+}
+
+func TestSalesService_CloseBasket_WhenTaxedBeforeDiscount_ThenDiscountShouldOnlyReduceTotals(t *testing.T) {
+	ts := newMockedServiceWithPolicy(models.DiscountTaxBeforeDiscount)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	discount := &models.Discount{
+		Name:   "Item Sale",
+		Kind:   models.DiscountKindPercentage,
+		Scope:  models.DiscountScopeItem,
+		ItemId: i.Id,
+		Rate:   decimal.NewFromFloat32(10),
+	}
+	_, err = ts.CreateDiscount(ctx, discount)
+	assert.NoError(t, err)
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 2)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(receipt.Discounts))
+	assert.Equal(t, i.Id, receipt.Discounts[0].ItemId)
+	assert.True(t, receipt.TotalDiscount.Equal(decimal.NewFromFloat32(4)))
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(4)))
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(36)))
+	assert.True(t, receipt.TotalGross.Equal(decimal.NewFromFloat32(40)))
+	assert.Equal(t, models.DiscountTaxBeforeDiscount, receipt.TaxPolicy)
+}
+
+func TestSalesService_CloseBasket_WhenTaxedAfterDiscount_ThenTaxesShouldBeCalculatedOnDiscountedPrices(t *testing.T) {
+	ts := newMockedServiceWithPolicy(models.DiscountTaxAfterDiscount)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	discount := &models.Discount{
+		Name:       "Category Sale",
+		Kind:       models.DiscountKindFixed,
+		Scope:      models.DiscountScopeCategory,
+		CategoryId: c.Id,
+		Amount:     decimal.NewFromFloat32(2),
+	}
+	_, err = ts.CreateDiscount(ctx, discount)
+	assert.NoError(t, err)
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 2)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	find, err := ts.GetReceiptByID(ctx, receipt.Id)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(find.Discounts))
+	assert.True(t, find.TotalDiscount.Equal(decimal.NewFromFloat32(4)))
+	assert.True(t, find.TotalTax.Equal(decimal.NewFromFloat32(3.6)))
+	assert.True(t, find.TotalPrice.Equal(decimal.NewFromFloat32(36)))
+	assert.True(t, find.TotalGross.Equal(decimal.NewFromFloat32(39.6)))
+	assert.True(t, find.TaxBreakdown[0].Base.Equal(decimal.NewFromFloat32(36)))
+
+	// items keep listed prices and taxes of listed prices
+	assert.True(t, find.Items[0].Price.Equal(decimal.NewFromFloat32(20)))
+	assert.True(t, find.Items[0].TotalTax().Equal(decimal.NewFromFloat32(4)))
+}
+
+func TestSalesService_CloseBasket_WhenTaxInclusiveItemTaxedBeforeDiscount_ThenDiscountShouldReduceShelfPrice(t *testing.T) {
+	ts := newMockedServiceWithPolicy(models.DiscountTaxBeforeDiscount)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:         "Test Item",
+		CategoryId:   c.Id,
+		Origin:       models.ItemOriginLocal,
+		Price:        decimal.NewFromFloat32(22),
+		TaxInclusive: true,
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	discount := &models.Discount{
+		Name:   "Item Sale",
+		Kind:   models.DiscountKindPercentage,
+		Scope:  models.DiscountScopeItem,
+		ItemId: i.Id,
+		Rate:   decimal.NewFromFloat32(10),
+	}
+	_, err = ts.CreateDiscount(ctx, discount)
+	assert.NoError(t, err)
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 2)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	// discount calculated on shelf price 44 which includes taxes
+	assert.True(t, receipt.TotalDiscount.Equal(decimal.NewFromFloat32(4.4)))
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(4)))
+	assert.True(t, receipt.TotalGross.Equal(decimal.NewFromFloat32(39.6)))
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(35.6)))
+	assert.True(t, receipt.TotalPrice.Add(receipt.TotalTax).Equal(receipt.TotalGross))
+}
+
+func TestSalesService_CloseBasket_WhenTaxInclusiveItemTaxedAfterDiscount_ThenTaxesShouldBeTakenOutOfDiscountedShelfPrice(t *testing.T) {
+	ts := newMockedServiceWithPolicy(models.DiscountTaxAfterDiscount)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:         "Test Item",
+		CategoryId:   c.Id,
+		Origin:       models.ItemOriginLocal,
+		Price:        decimal.NewFromFloat32(22),
+		TaxInclusive: true,
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	discount := &models.Discount{
+		Name:   "Item Sale",
+		Kind:   models.DiscountKindPercentage,
+		Scope:  models.DiscountScopeItem,
+		ItemId: i.Id,
+		Rate:   decimal.NewFromFloat32(10),
+	}
+	_, err = ts.CreateDiscount(ctx, discount)
+	assert.NoError(t, err)
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 2)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	// discount calculated on shelf price 44 which includes taxes
+	assert.True(t, receipt.TotalDiscount.Equal(decimal.NewFromFloat32(4.4)))
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(3.6)))
+	assert.True(t, receipt.TotalGross.Equal(decimal.NewFromFloat32(39.6)))
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(36)))
+	assert.True(t, receipt.TotalPrice.Add(receipt.TotalTax).Equal(receipt.TotalGross))
+}
+
+func TestSalesService_CloseBasket_WhenBuyXGetYDiscount_ThenEveryYUnitsShouldBeFree(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	discount := &models.Discount{
+		Name:   "Buy 2 Get 1",
+		Scope:  models.DiscountScopeBuyXGetY,
+		ItemId: i.Id,
+		Buy:    2,
+		Get:    1,
+	}
+	_, err = ts.CreateDiscount(ctx, discount)
+	assert.NoError(t, err)
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 5)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	// every third unit is free, so one of five units is free and paid 80 is shared as 16 per unit
+	assert.True(t, receipt.TotalDiscount.Equal(decimal.NewFromFloat32(20)))
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(80)))
+	assert.True(t, receipt.TotalGross.Equal(receipt.TotalPrice.Add(receipt.TotalTax)))
+}
+
+func TestSalesService_CloseBasket_WhenBasketDiscount_ThenShouldShareDiscountByItems(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	discount := &models.Discount{
+		Name:   "Welcome",
+		Kind:   models.DiscountKindFixed,
+		Scope:  models.DiscountScopeBasket,
+		Amount: decimal.NewFromFloat32(5),
+	}
+	_, err = ts.CreateDiscount(ctx, discount)
+	assert.NoError(t, err)
+
+	other := &models.InventoryItem{
+		Name:       "Other Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(30),
+	}
+	other, err = ts.is.CreateItem(ctx, other)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, other.Id, 1)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	// 5 shared as 2 and 3, taxes 1.8 and 2.7
+	assert.Equal(t, 1, len(receipt.Discounts))
+	assert.Equal(t, uuid.Nil, receipt.Discounts[0].ItemId)
+	assert.True(t, receipt.TotalDiscount.Equal(decimal.NewFromFloat32(5)))
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(4.5)))
+	assert.True(t, receipt.TotalGross.Equal(decimal.NewFromFloat32(49.5)))
+}
+
+func TestSalesService_CreateDiscount_WhenDiscountInvalid_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	cases := map[error]*models.Discount{
+		sales.ErrInvalidDiscountName:   {Kind: models.DiscountKindFixed, Scope: models.DiscountScopeBasket, Amount: decimal.NewFromFloat32(5)},
+		sales.ErrInvalidDiscountScope:  {Name: "Sale", Kind: models.DiscountKindFixed, Amount: decimal.NewFromFloat32(5)},
+		sales.ErrInvalidDiscountKind:   {Name: "Sale", Scope: models.DiscountScopeBasket},
+		sales.ErrInvalidDiscountRate:   {Name: "Sale", Kind: models.DiscountKindPercentage, Scope: models.DiscountScopeBasket, Rate: decimal.NewFromFloat32(110)},
+		sales.ErrInvalidDiscountAmount: {Name: "Sale", Kind: models.DiscountKindFixed, Scope: models.DiscountScopeBasket},
+		inventory.ErrInvalidItemId:     {Name: "Sale", Kind: models.DiscountKindFixed, Scope: models.DiscountScopeItem, ItemId: uuid.NewV1(), Amount: decimal.NewFromFloat32(5)},
+		inventory.ErrInvalidCategoryId: {Name: "Sale", Kind: models.DiscountKindFixed, Scope: models.DiscountScopeCategory, CategoryId: uuid.NewV1(), Amount: decimal.NewFromFloat32(5)},
+	}
+
+	for expected, discount := range cases {
+		_, err := ts.CreateDiscount(ctx, discount)
+		assert.Equal(t, expected, err)
+	}
+
+	c, err := ts.is.CreateCategory(ctx, &models.Category{Name: "Test Category"})
+	assert.NoError(t, err, "failed to add category")
+
+	item, err := ts.is.CreateItem(ctx, &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	})
+	assert.NoError(t, err, "failed to add item")
+
+	_, err = ts.CreateDiscount(ctx, &models.Discount{Name: "Buy 2 Get 1", Scope: models.DiscountScopeBuyXGetY, ItemId: item.Id, Buy: 2})
+	assert.Equal(t, sales.ErrInvalidDiscountQuantity, err)
+}
+
+func TestSalesService_ApplyCoupon_WhenCouponApplied_ThenDiscountShouldBeAppliedOnClose(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	d := &models.Discount{
+		Name:       "Coupon Sale",
+		Kind:       models.DiscountKindPercentage,
+		Scope:      models.DiscountScopeBasket,
+		Rate:       decimal.NewFromFloat32(10),
+		CouponOnly: true,
+	}
+	d, err := ts.CreateDiscount(ctx, d)
+	assert.NoError(t, err)
+
+	coupon := &models.Coupon{
+		Code:       " summer10 ",
+		DiscountId: d.Id,
+		MaxUses:    2,
+	}
+	_, err = ts.CreateCoupon(ctx, coupon)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.ApplyCoupon(ctx, bid, "Summer10")
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"SUMMER10"}, receipt.Coupons)
+	assert.True(t, receipt.TotalDiscount.Equal(decimal.NewFromFloat32(2)))
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(18)))
+
+	find, err := ts.GetCouponByCode(ctx, "summer10")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, find.Uses)
+}
+
+func TestSalesService_CloseBasket_WhenCouponNotApplied_ThenCouponOnlyDiscountShouldNotBeApplied(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	d := &models.Discount{
+		Name:       "Coupon Sale",
+		Kind:       models.DiscountKindPercentage,
+		Scope:      models.DiscountScopeBasket,
+		Rate:       decimal.NewFromFloat32(10),
+		CouponOnly: true,
+	}
+	d, err := ts.CreateDiscount(ctx, d)
+	assert.NoError(t, err)
+
+	coupon := &models.Coupon{
+		Code:       "SUMMER10",
+		DiscountId: d.Id,
+	}
+	_, err = ts.CreateCoupon(ctx, coupon)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, len(receipt.Discounts))
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(20)))
+}
+
+func TestSalesService_ApplyCoupon_WhenCouponCanNotBeUsed_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	d := &models.Discount{
+		Name:       "Coupon Sale",
+		Kind:       models.DiscountKindPercentage,
+		Scope:      models.DiscountScopeBasket,
+		Rate:       decimal.NewFromFloat32(10),
+		CouponOnly: true,
+	}
+	d, err := ts.CreateDiscount(ctx, d)
+	assert.NoError(t, err)
+
+	coupon := &models.Coupon{
+		Code:       "ONCE",
+		DiscountId: d.Id,
+		MaxUses:    1,
+	}
+	_, err = ts.CreateCoupon(ctx, coupon)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.CreateCoupon(ctx, &models.Coupon{Code: "EXPIRED", DiscountId: d.Id, ExpiresAt: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+
+	_, err = ts.ApplyCoupon(ctx, bid, "UNKNOWN")
+	assert.Equal(t, sales.ErrCouponNotFound, err)
+
+	_, err = ts.ApplyCoupon(ctx, bid, "EXPIRED")
+	assert.Equal(t, sales.ErrCouponExpired, err)
+
+	_, err = ts.ApplyCoupon(ctx, bid, "ONCE")
+	assert.NoError(t, err)
+
+	_, err = ts.ApplyCoupon(ctx, bid, "once")
+	assert.Equal(t, sales.ErrCouponAlreadyApplied, err)
+
+	_, err = ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	other, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, other, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.ApplyCoupon(ctx, other, "ONCE")
+	assert.Equal(t, sales.ErrCouponUsedUp, err)
+}
+
+func TestSalesService_CloseBasket_WhenBasketsWithLimitedCouponClosedConcurrently_ThenShouldNotUseCouponOverLimit(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	d := &models.Discount{
+		Name:       "Coupon Sale",
+		Kind:       models.DiscountKindPercentage,
+		Scope:      models.DiscountScopeBasket,
+		Rate:       decimal.NewFromFloat32(10),
+		CouponOnly: true,
+	}
+	d, err := ts.CreateDiscount(ctx, d)
+	assert.NoError(t, err)
+
+	coupon := &models.Coupon{
+		Code:       "ONCE",
+		DiscountId: d.Id,
+		MaxUses:    1,
+	}
+	_, err = ts.CreateCoupon(ctx, coupon)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	// coupon applies to all baskets while none of them closed
+	bids := make([]uuid.UUID, 5)
+	for n := range bids {
+		bid, err := ts.CreateBasket(ctx, uuid.Nil)
+		assert.NoError(t, err)
+
+		_, err = ts.AddItem(ctx, bid, i.Id, 1)
+		assert.NoError(t, err)
+
+		_, err = ts.ApplyCoupon(ctx, bid, "ONCE")
+		assert.NoError(t, err)
+
+		bids[n] = bid
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(bids))
+
+	for n, bid := range bids {
+		wg.Add(1)
+		go func(n int, bid uuid.UUID) {
+			defer wg.Done()
+			_, errs[n] = ts.CloseBasket(ctx, bid)
+		}(n, bid)
+	}
+	wg.Wait()
+
+	closed := 0
+	for _, err := range errs {
+		if err == nil {
+			closed++
+			continue
+		}
+		assert.Equal(t, sales.ErrCouponUsedUp, err)
+	}
+	assert.Equal(t, 1, closed)
+
+	find, err := ts.GetCouponByCode(ctx, "ONCE")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, find.Uses)
+}
+
+func TestSalesService_CreateCoupon_WhenCouponInvalid_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	auto, err := ts.CreateDiscount(ctx, &models.Discount{
+		Name:   "Sale",
+		Kind:   models.DiscountKindFixed,
+		Scope:  models.DiscountScopeBasket,
+		Amount: decimal.NewFromFloat32(5),
+	})
+	assert.NoError(t, err)
+
+	_, err = ts.CreateCoupon(ctx, &models.Coupon{Code: " ", DiscountId: auto.Id})
+	assert.Equal(t, sales.ErrInvalidCouponCode, err)
+
+	_, err = ts.CreateCoupon(ctx, &models.Coupon{Code: "SALE", DiscountId: auto.Id, MaxUses: -1})
+	assert.Equal(t, sales.ErrInvalidCouponLimit, err)
+
+	_, err = ts.CreateCoupon(ctx, &models.Coupon{Code: "SALE", DiscountId: auto.Id})
+	assert.Equal(t, sales.ErrInvalidCouponDiscount, err)
+
+	_, err = ts.CreateCoupon(ctx, &models.Coupon{Code: "SALE", DiscountId: uuid.NewV1()})
+	assert.Equal(t, sales.ErrInvalidCouponDiscount, err)
+}
+
+func TestSalesService_CloseBasket_WhenPricesLockedAtAdd_ThenShouldKeepPricesOfFirstAdd(t *testing.T) {
+	ts := newMockedServiceWithPolicies(models.DiscountTaxAfterDiscount, models.RepricingLockAtAdd)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	// second unit added after price of the item raised to 30 and tax to 20 percent
+	i.Price = decimal.NewFromFloat32(30)
+	_, err = ts.is.UpdateItem(ctx, i)
+	assert.NoError(t, err, "failed to update item")
+
+	tax.Rate = decimal.NewFromFloat32(20)
+	_, err = ts.ts.UpdateTax(ctx, tax)
+	assert.NoError(t, err, "failed to update tax")
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(40)))
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(4)))
+	assert.False(t, receipt.Items[0].PricedAt.IsZero())
+}
+
+func TestSalesService_CloseBasket_WhenRepricedAtClose_ThenShouldUseCurrentPrices(t *testing.T) {
+	ts := newMockedServiceWithPolicies(models.DiscountTaxAfterDiscount, models.RepricingAtClose)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	// second unit added after price of the item raised to 30 and tax to 20 percent
+	i.Price = decimal.NewFromFloat32(30)
+	_, err = ts.is.UpdateItem(ctx, i)
+	assert.NoError(t, err, "failed to update item")
+
+	tax.Rate = decimal.NewFromFloat32(20)
+	_, err = ts.ts.UpdateTax(ctx, tax)
+	assert.NoError(t, err, "failed to update tax")
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(60)))
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(12)))
+}
+
+func TestSalesService_Reprice_WhenPricesLockedAtAdd_ThenShouldUseCurrentPrices(t *testing.T) {
+	ts := newMockedServiceWithPolicies(models.DiscountTaxAfterDiscount, models.RepricingLockAtAdd)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	// second unit added after price of the item raised to 30 and tax to 20 percent
+	i.Price = decimal.NewFromFloat32(30)
+	_, err = ts.is.UpdateItem(ctx, i)
+	assert.NoError(t, err, "failed to update item")
+
+	tax.Rate = decimal.NewFromFloat32(20)
+	_, err = ts.ts.UpdateTax(ctx, tax)
+	assert.NoError(t, err, "failed to update tax")
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	basket, err := ts.Reprice(ctx, bid)
+	assert.NoError(t, err)
+	assert.True(t, basket.Items[i.Id].Price.Equal(decimal.NewFromFloat32(30)))
+	assert.True(t, basket.Items[i.Id].Taxes.Equal(decimal.NewFromFloat32(6)))
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	assert.True(t, receipt.TotalPrice.Equal(decimal.NewFromFloat32(60)))
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(12)))
+}
+
+func TestSalesService_Reprice_WhenRateChangeScheduledAfterBasketOpened_ThenShouldUseNewRate(t *testing.T) {
+	ts := newMockedServiceWithPolicies(models.DiscountTaxAfterDiscount, models.RepricingLockAtAdd)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.ts.ScheduleRateChange(ctx, tax.Id, decimal.NewFromFloat32(20), time.Now().Add(50*time.Millisecond))
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	basket, err := ts.Reprice(ctx, bid)
+	assert.NoError(t, err)
+	assert.True(t, basket.Items[i.Id].Taxes.Equal(decimal.NewFromFloat32(4)))
+
+	// items added after repricing priced with the new rate as well
+	i2, err := ts.is.CreateItem(ctx, &models.InventoryItem{
+		Name:       "Test Item 2",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(10),
+	})
+	assert.NoError(t, err, "failed to add item")
+
+	_, err = ts.AddItem(ctx, bid, i2.Id, 1)
+	assert.NoError(t, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(6)))
+}
+
+func TestSalesService_CloseBasket_WhenRepricedAtCloseAfterRateChange_ThenShouldUseNewRate(t *testing.T) {
+	ts := newMockedServiceWithPolicies(models.DiscountTaxAfterDiscount, models.RepricingAtClose)
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.ts.ScheduleRateChange(ctx, tax.Id, decimal.NewFromFloat32(20), time.Now().Add(50*time.Millisecond))
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+	assert.True(t, receipt.TotalTax.Equal(decimal.NewFromFloat32(4)))
+}
+
+func TestSalesService_Reprice_WhenItemDeleted_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	tax := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	_, err := ts.ts.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.is.DeleteItem(ctx, i.Id)
+	assert.NoError(t, err)
+
+	_, err = ts.Reprice(ctx, bid)
+	assert.Equal(t, inventory.ErrInvalidItemId, err)
+}
+
+func TestSalesService_AddItem_WhenBasketVersionIsStale_ThenShouldReturnConflict(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err := ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	basket, err := ts.GetBasketByID(ctx, bid)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(sales.WithBasketVersion(ctx, basket.Version), bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(sales.WithBasketVersion(ctx, basket.Version), bid, i.Id, 1)
+	assert.Equal(t, sales.ErrBasketConflict, err)
+
+	basket, err = ts.GetBasketByID(ctx, bid)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, basket.Items[i.Id].Count)
+}
+
+func TestSalesService_AddItem_ThenShouldReturnSavedBasketVersion(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err := ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	basket, err := ts.AddItem(ctx, bid, i.Id, 2)
+	assert.NoError(t, err)
+
+	find, err := ts.GetBasketByID(ctx, bid)
+	assert.NoError(t, err)
+	assert.Equal(t, find.Version, basket.Version)
+
+	// version returned by each write is enough for the next one
+	basket, err = ts.RemoveItem(sales.WithBasketVersion(ctx, basket.Version), bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	basket, err = ts.CancelBasket(sales.WithBasketVersion(ctx, basket.Version), bid)
+	assert.NoError(t, err)
+	assert.Equal(t, models.BasketStateCancelled, basket.State)
+
+	find, err = ts.GetBasketByID(ctx, bid)
+	assert.NoError(t, err)
+	assert.Equal(t, find.Version, basket.Version)
+}
+
+func TestSalesService_AddItem_WhenBasketUpdatedConcurrently_ThenShouldNotLoseUpdates(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err := ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		added   = 1
+		workers = 20
+	)
+
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := ts.AddItem(ctx, bid, i.Id, 1)
+			if err == sales.ErrBasketConflict {
+				return
+			}
+			assert.NoError(t, err)
+
+			mu.Lock()
+			added++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	basket, err := ts.GetBasketByID(ctx, bid)
+	assert.NoError(t, err)
+	assert.Equal(t, added, basket.Items[i.Id].Count)
+}
+
+func TestSalesService_CloseBasket_WhenClosedConcurrently_ThenShouldCreateOnlyOneReceipt(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	d := &models.Discount{
+		Name:       "Coupon Sale",
+		Kind:       models.DiscountKindPercentage,
+		Scope:      models.DiscountScopeBasket,
+		Rate:       decimal.NewFromFloat32(10),
+		CouponOnly: true,
+	}
+	d, err := ts.CreateDiscount(ctx, d)
+	assert.NoError(t, err)
+
+	coupon := &models.Coupon{
+		Code:       "SUMMER10",
+		DiscountId: d.Id,
+		MaxUses:    10,
+	}
+	_, err = ts.CreateCoupon(ctx, coupon)
+	assert.NoError(t, err)
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err = ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.ApplyCoupon(ctx, bid, "SUMMER10")
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := ts.CloseBasket(ctx, bid)
+			if err != nil {
+				assert.Contains(t, []error{sales.ErrBasketConflict, sales.ErrBasketNotOpen}, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	list, err := ts.FetchAllReceipts(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(list))
+
+	find, err := ts.GetCouponByCode(ctx, "SUMMER10")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, find.Uses)
+}
+
+func TestSalesService_GetReceiptByBasketID_WhenBasketClosed_ThenShouldReturnReceiptOfBasket(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err := ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	_, err = ts.GetReceiptByBasketID(ctx, bid)
+	assert.Equal(t, sales.ErrBasketNotClosed, err)
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	find, err := ts.GetReceiptByBasketID(ctx, bid)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.Id, find.Id)
+	assert.Equal(t, bid, find.BasketId)
+	assert.Equal(t, uuid.Nil, find.ZoneId)
+	assert.False(t, find.IssuedAt.IsZero())
+
+	_, err = ts.GetReceiptByBasketID(ctx, uuid.NewV1())
+	assert.Equal(t, sales.ErrInvalidBasketId, err)
+}
+
+func TestSalesService_FetchReceiptsIssuedBetween(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	c := &models.Category{
+		Name: "Test Category",
+	}
+	c, err := ts.is.CreateCategory(ctx, c)
+	assert.NoError(t, err, "failed to add category")
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: c.Id,
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(20),
+	}
+	i, err = ts.is.CreateItem(ctx, i)
+	assert.NoError(t, err, "failed to add item")
+
+	bid, err := ts.CreateBasket(ctx, uuid.Nil)
+	assert.NoError(t, err)
+
+	_, err = ts.AddItem(ctx, bid, i.Id, 1)
+	assert.NoError(t, err)
+
+	before := time.Now()
+
+	receipt, err := ts.CloseBasket(ctx, bid)
+	assert.NoError(t, err)
+
+	list, err := ts.FetchReceiptsIssuedBetween(ctx, before, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, receipt.Id, list[0].Id)
+
+	list, err = ts.FetchReceiptsIssuedBetween(ctx, time.Now().Add(time.Minute), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(list))
+
+	_, err = ts.FetchReceiptsIssuedBetween(ctx, before, before)
+	assert.Equal(t, sales.ErrInvalidReceiptRange, err)
+}
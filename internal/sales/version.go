@@ -0,0 +1,16 @@
+package sales
+
+import "context"
+
+type basketVersionKey struct{}
+
+// WithBasketVersion returns a copy of context carrying the basket version the caller expects to update
+func WithBasketVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, basketVersionKey{}, version)
+}
+
+// BasketVersionFromContext returns the basket version carried by context, false if caller doesn't expect a version
+func BasketVersionFromContext(ctx context.Context) (int, bool) {
+	version, ok := ctx.Value(basketVersionKey{}).(int)
+	return version, ok
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/aweris/stp/internal/inventory"
+	"github.com/aweris/stp/internal/models"
 	"github.com/aweris/stp/internal/sales"
 	"github.com/aweris/stp/internal/taxes"
 	"github.com/aweris/stp/storage"
@@ -24,8 +25,27 @@ type Server struct {
 	SaleService      sales.SalesService
 }
 
+// Option configures optional settings of the server
+type Option func(*options)
+
+type options struct {
+	taxMode models.TaxCalculationMode
+}
+
+// WithTaxCalculationMode sets how taxes of sale items calculated, taxes are calculated in combined mode by default
+func WithTaxCalculationMode(mode models.TaxCalculationMode) Option {
+	return func(o *options) {
+		o.taxMode = mode
+	}
+}
+
 // NewServer creates and configures with boltDB storage
-func NewServer(storagePath string) *Server {
+func NewServer(storagePath string, opts ...Option) *Server {
+	o := &options{taxMode: models.TaxCalculationCombined}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	db, err := storage.NewBoltDB(storagePath)
 	if err != nil {
 		return nil
@@ -33,16 +53,19 @@ func NewServer(storagePath string) *Server {
 	cr := inventoryRepo.NewBoltDBCategoryRepository(db)
 	ir := inventoryRepo.NewBoltDBItemRepository(db)
 
-	is := inventoryService.NewInventoryService(ir, cr)
-
-	tr := taxRepo.NewBoltDBTaxRepository(db)
+	tr := taxRepo.NewCachedTaxRepository(taxRepo.NewBoltDBTaxRepository(db))
+	thr := taxRepo.NewBoltDBTaxHistoryRepository(db)
+	zr := taxRepo.NewBoltDBZoneRepository(db)
 
-	ts := taxService.NewTaxService(tr)
+	ts := taxService.NewTaxService(tr, thr, zr, cr, db, o.taxMode)
+	is := inventoryService.NewInventoryService(ir, cr, ts, db)
 
 	br := salesRepository.NewBoltDBBasketRepository(db)
 	rr := salesRepository.NewBoltDBReceiptRepository(db)
+	dr := salesRepository.NewBoltDBDiscountRepository(db)
+	cpr := salesRepository.NewBoltDBCouponRepository(db)
 
-	ss := salesService.NewSalesService(br, rr, is, ts)
+	ss := salesService.NewSalesService(br, rr, dr, cpr, db, is, ts, models.DiscountTaxAfterDiscount, models.RepricingLockAtAdd)
 
 	s := &Server{
 		db:               db,
@@ -0,0 +1,72 @@
+package server_test
+
+import (
+	"context"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/server"
+	"github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, opts ...server.Option) (*server.Server, func()) {
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(t, err)
+
+	s := server.NewServer(filepath.Join(dir, "test.store"), opts...)
+	assert.NotNil(t, s)
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// taxesOfPerfume returns taxes of an imported item priced 1.10 subject to 10 percent sales tax and 5 percent import duty
+func taxesOfPerfume(t *testing.T, s *server.Server) decimal.Decimal {
+	ctx := context.Background()
+
+	_, err := s.TaxService.CreateTax(ctx, &models.Tax{Name: "Basic Sales Tax", Rate: decimal.NewFromFloat32(10), Origin: models.TaxOriginAll})
+	assert.NoError(t, err)
+
+	_, err = s.TaxService.CreateTax(ctx, &models.Tax{Name: "Import Duty", Rate: decimal.NewFromFloat32(5), Origin: models.TaxOriginImport})
+	assert.NoError(t, err)
+
+	i := &models.InventoryItem{
+		Name:       "Perfume",
+		CategoryId: uuid.NewV1(),
+		Origin:     models.ItemOriginImported,
+		Price:      decimal.NewFromFloat32(1.10),
+	}
+
+	si, err := s.TaxService.GetSaleItem(ctx, i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+
+	return si.Taxes
+}
+
+func TestNewServer_WhenModeNotGiven_ThenShouldCalculateTaxesCombined(t *testing.T) {
+	s, closeFn := newTestServer(t)
+	defer closeFn()
+
+	assert.True(t, taxesOfPerfume(t, s).Equal(decimal.NewFromFloat32(0.2)))
+}
+
+func TestNewServer_WhenModeIsCombined_ThenShouldCalculateTaxesCombined(t *testing.T) {
+	s, closeFn := newTestServer(t, server.WithTaxCalculationMode(models.TaxCalculationCombined))
+	defer closeFn()
+
+	assert.True(t, taxesOfPerfume(t, s).Equal(decimal.NewFromFloat32(0.2)))
+}
+
+func TestNewServer_WhenModeIsPerTax_ThenShouldCalculateEachTaxIndividually(t *testing.T) {
+	s, closeFn := newTestServer(t, server.WithTaxCalculationMode(models.TaxCalculationPerTax))
+	defer closeFn()
+
+	assert.True(t, taxesOfPerfume(t, s).Equal(decimal.NewFromFloat32(0.25)))
+}
@@ -5,7 +5,22 @@ import "errors"
 var (
 	ErrInvalidParameter = errors.New("invalid parameter")
 
-	ErrInvalidTaxId   = errors.New("invalid tax id")
-	ErrInvalidTaxName = errors.New("invalid tax name")
-	ErrInvalidTaxRate = errors.New("invalid tax rate")
+	ErrInvalidTaxId     = errors.New("invalid tax id")
+	ErrInvalidTaxName   = errors.New("invalid tax name")
+	ErrInvalidTaxRate   = errors.New("invalid tax rate")
+	ErrInvalidTaxKind   = errors.New("invalid tax kind")
+	ErrInvalidTaxAmount = errors.New("invalid tax amount")
+
+	ErrInvalidTaxRounding = errors.New("invalid tax rounding rule")
+	ErrInvalidTaxValidity = errors.New("invalid tax validity period")
+	ErrInvalidTaxRule     = errors.New("invalid tax rule")
+	ErrInvalidTaxCategory = errors.New("invalid tax category")
+	ErrTaxConflict        = errors.New("tax conflicts with an existing tax")
+
+	ErrInvalidTaxOrigin    = errors.New("invalid tax origin")
+	ErrInvalidTaxCondition = errors.New("invalid tax condition")
+
+	ErrInvalidZoneId   = errors.New("invalid zone id")
+	ErrInvalidZoneName = errors.New("invalid zone name")
+	ErrZoneInUse       = errors.New("zone is used by taxes")
 )
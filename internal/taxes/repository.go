@@ -4,12 +4,30 @@ import (
 	"context"
 	"github.com/aweris/stp/internal/models"
 	"github.com/satori/go.uuid"
+	"time"
 )
 
+// Transactor runs changes of repositories in a single transaction, repositories join it through the given context
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type TaxRepository interface {
 	SaveTax(ctx context.Context, tax *models.Tax) (*models.Tax, error)
 	GetTaxByID(ctx context.Context, taxId uuid.UUID) (*models.Tax, error)
-	GetTaxesByItemOriginAndCategory(ctx context.Context, origin models.ItemOrigin, categoryId uuid.UUID) ([]*models.Tax, error)
+	GetTaxesByItemOriginAndCategory(ctx context.Context, origin models.ItemOrigin, categoryId uuid.UUID, at time.Time) ([]*models.Tax, error)
 	FetchAllTaxes(ctx context.Context) ([]*models.Tax, error)
 	DeleteTax(ctx context.Context, taxId uuid.UUID) (*models.Tax, error)
 }
+
+type TaxHistoryRepository interface {
+	SaveTaxVersion(ctx context.Context, version *models.TaxVersion) (*models.TaxVersion, error)
+	GetTaxHistory(ctx context.Context, taxId uuid.UUID) ([]*models.TaxVersion, error)
+}
+
+type ZoneRepository interface {
+	SaveZone(ctx context.Context, zone *models.Zone) (*models.Zone, error)
+	GetZoneByID(ctx context.Context, zoneId uuid.UUID) (*models.Zone, error)
+	FetchAllZones(ctx context.Context) ([]*models.Zone, error)
+	DeleteZone(ctx context.Context, zoneId uuid.UUID) (*models.Zone, error)
+}
@@ -9,23 +9,76 @@ import (
 	"github.com/satori/go.uuid"
 	"go.etcd.io/bbolt"
 	"log"
+	"time"
 )
 
 const (
-	bucketTax = "taxes_tax"
+	bucketTax               = "taxes_tax"
+	bucketTaxMeta           = "_meta"
+	bucketTaxIdx            = "index"
+	bucketTaxIdxTaxOrigin   = "idx_tax_origin"
+	bucketTaxIdxTaxCategory = "idx_tax_category"
 )
 
+// legacyCondition is the condition saved for taxes with unknown conditions before conditions were validated, such
+// taxes apply to all categories
+const legacyCondition = "UNKNOWN"
+
 type boltDBTaxRepository struct {
 	db *storage.BoltDB
 }
 
 func (tr *boltDBTaxRepository) init() error {
 	return tr.db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(bucketTax))
+		tb, err := tx.CreateBucketIfNotExists([]byte(bucketTax))
 		if err != nil {
 			return err
 		}
-		return nil
+
+		mt, err := tb.CreateBucketIfNotExists([]byte(bucketTaxMeta))
+		if err != nil {
+			return err
+		}
+
+		ib, err := mt.CreateBucketIfNotExists([]byte(bucketTaxIdx))
+		if err != nil {
+			return err
+		}
+
+		// taxes saved before indexes introduced needs to be indexed once
+		reindex := ib.Bucket([]byte(bucketTaxIdxTaxOrigin)) == nil
+
+		_, err = ib.CreateBucketIfNotExists([]byte(bucketTaxIdxTaxOrigin))
+		if err != nil {
+			return err
+		}
+
+		_, err = ib.CreateBucketIfNotExists([]byte(bucketTaxIdxTaxCategory))
+		if err != nil {
+			return err
+		}
+
+		// taxes with legacy condition can't be decoded, they're migrated before anything reads them
+		err = migrateTaxConditions(tb)
+		if err != nil {
+			return err
+		}
+
+		if !reindex {
+			return nil
+		}
+
+		return tb.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil
+			}
+			var tax models.Tax
+			err := json.Unmarshal(v, &tax)
+			if err != nil {
+				return err
+			}
+			return indexTax(tb, &tax)
+		})
 	})
 }
 
@@ -40,22 +93,40 @@ func NewBoltDBTaxRepository(db *storage.BoltDB) taxes.TaxRepository {
 }
 
 func (btr *boltDBTaxRepository) SaveTax(ctx context.Context, tax *models.Tax) (*models.Tax, error) {
-	err := btr.db.Update(func(tx *bolt.Tx) error {
+	err := btr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketTax))
 
+		// removing index entries of the previous version of the tax
+		if v := tb.Get(tax.Id.Bytes()); v != nil {
+			var existing models.Tax
+			err := json.Unmarshal(v, &existing)
+			if err != nil {
+				return err
+			}
+			err = unindexTax(tb, &existing)
+			if err != nil {
+				return err
+			}
+		}
+
 		data, err := json.Marshal(tax)
 		if err != nil {
 			return err
 		}
 
-		return tb.Put(tax.Id.Bytes(), data)
+		err = tb.Put(tax.Id.Bytes(), data)
+		if err != nil {
+			return err
+		}
+
+		return indexTax(tb, tax)
 	})
 	return tax, err
 }
 
 func (tr *boltDBTaxRepository) GetTaxByID(ctx context.Context, taxId uuid.UUID) (*models.Tax, error) {
 	var tax *models.Tax
-	err := tr.db.View(func(tx *bolt.Tx) error {
+	err := tr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketTax))
 
 		v := tb.Get(taxId.Bytes())
@@ -71,34 +142,65 @@ func (tr *boltDBTaxRepository) GetTaxByID(ctx context.Context, taxId uuid.UUID)
 	return tax, err
 }
 
-func (tr *boltDBTaxRepository) GetTaxesByItemOriginAndCategory(ctx context.Context, origin models.ItemOrigin, categoryId uuid.UUID) ([]*models.Tax, error) {
+func (tr *boltDBTaxRepository) GetTaxesByItemOriginAndCategory(ctx context.Context, origin models.ItemOrigin, categoryId uuid.UUID, at time.Time) ([]*models.Tax, error) {
 	var txs = make([]*models.Tax, 0)
-	err := tr.db.View(func(tx *bolt.Tx) error {
+	err := tr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketTax))
 
-		tb.ForEach(func(k, v []byte) error {
-			var tax *models.Tax
-			err := json.Unmarshal(v, &tax)
-			if err != nil {
-				return err
+		// getting index buckets
+		mb := tb.Bucket([]byte(bucketTaxMeta))
+		ib := mb.Bucket([]byte(bucketTaxIdx))
+		idxO := ib.Bucket([]byte(bucketTaxIdxTaxOrigin))
+		idxC := ib.Bucket([]byte(bucketTaxIdxTaxCategory))
+
+		// taxes listing given category, nil if category not referenced by any tax
+		idxTC := idxC.Bucket(categoryId.Bytes())
+
+		origins := []models.TaxOrigin{models.TaxOrigin(origin)}
+		if origins[0] != models.TaxOriginAll {
+			origins = append(origins, models.TaxOriginAll)
+		}
+
+		for _, o := range origins {
+			idxTO := idxO.Bucket([]byte(o))
+			if idxTO == nil {
+				continue
 			}
 
-			if string(tax.Origin) == string(origin) || tax.Origin == models.TaxOriginAll {
-				if (tax.Condition == models.UnknownTC) {
-					txs = append(txs, tax)
+			// index value keeps the condition of the tax, so category check doesn't need tax itself
+			err := idxTO.ForEach(func(k, v []byte) error {
+				if v == nil {
 					return nil
 				}
 
-				exist := tax.Categories[categoryId]
+				listed := idxTC != nil && idxTC.Get(k) != nil
 
-				if (tax.Condition == models.SubjectToTax && exist) || (tax.Condition == models.ExemptToTax && !exist) {
-					txs = append(txs, tax)
-					return nil
+				switch models.TaxCondition(v) {
+				case models.SubjectToTax:
+					if !listed {
+						return nil
+					}
+				case models.ExemptToTax:
+					if listed {
+						return nil
+					}
 				}
-			}
 
-			return nil
-		})
+				var tax models.Tax
+				err := json.Unmarshal(tb.Get(k), &tax)
+				if err != nil {
+					return err
+				}
+
+				if tax.IsValidAt(at) {
+					txs = append(txs, &tax)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
 
 		return nil
 	})
@@ -107,7 +209,7 @@ func (tr *boltDBTaxRepository) GetTaxesByItemOriginAndCategory(ctx context.Conte
 
 func (tr *boltDBTaxRepository) FetchAllTaxes(ctx context.Context) ([]*models.Tax, error) {
 	var txs = make([]*models.Tax, 0)
-	err := tr.db.View(func(tx *bolt.Tx) error {
+	err := tr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketTax))
 
 		return tb.ForEach(func(k, v []byte) error {
@@ -128,7 +230,7 @@ func (tr *boltDBTaxRepository) FetchAllTaxes(ctx context.Context) ([]*models.Tax
 
 func (tr *boltDBTaxRepository) DeleteTax(ctx context.Context, taxId uuid.UUID) (*models.Tax, error) {
 	var existing *models.Tax
-	err := tr.db.Update(func(tx *bolt.Tx) error {
+	err := tr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
 		tb := tx.Bucket([]byte(bucketTax))
 
 		v := tb.Get(taxId.Bytes())
@@ -140,7 +242,162 @@ func (tr *boltDBTaxRepository) DeleteTax(ctx context.Context, taxId uuid.UUID) (
 			return err
 		}
 
+		err = unindexTax(tb, existing)
+		if err != nil {
+			return err
+		}
+
 		return tb.Delete(taxId.Bytes())
 	})
 	return existing, err
 }
+
+// indexTax adds tax id under origin and category index buckets
+func indexTax(tb *bolt.Bucket, tax *models.Tax) error {
+	// getting index bucket
+	mb := tb.Bucket([]byte(bucketTaxMeta))
+	ib := mb.Bucket([]byte(bucketTaxIdx))
+
+	idxTO, err := ib.Bucket([]byte(bucketTaxIdxTaxOrigin)).CreateBucketIfNotExists([]byte(tax.Origin))
+	if err != nil {
+		return err
+	}
+	err = idxTO.Put(tax.Id.Bytes(), []byte(tax.Condition))
+	if err != nil {
+		return err
+	}
+
+	idxC := ib.Bucket([]byte(bucketTaxIdxTaxCategory))
+	for categoryId, listed := range tax.Categories {
+		if !listed {
+			continue
+		}
+		idxTC, err := idxC.CreateBucketIfNotExists(categoryId.Bytes())
+		if err != nil {
+			return err
+		}
+		err = idxTC.Put(tax.Id.Bytes(), []byte("true"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexTax removes tax id from origin and category index buckets
+func unindexTax(tb *bolt.Bucket, tax *models.Tax) error {
+	// getting index bucket
+	mb := tb.Bucket([]byte(bucketTaxMeta))
+	ib := mb.Bucket([]byte(bucketTaxIdx))
+
+	if idxTO := ib.Bucket([]byte(bucketTaxIdxTaxOrigin)).Bucket([]byte(tax.Origin)); idxTO != nil {
+		err := idxTO.Delete(tax.Id.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	idxC := ib.Bucket([]byte(bucketTaxIdxTaxCategory))
+	for categoryId := range tax.Categories {
+		idxTC := idxC.Bucket(categoryId.Bytes())
+		if idxTC == nil {
+			continue
+		}
+		err := idxTC.Delete(tax.Id.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTaxConditions replaces legacy conditions of the taxes with all categories and indexes them again
+func migrateTaxConditions(tb *bolt.Bucket) error {
+	legacy := make(map[string][]byte)
+	migrated := make(map[string][]byte)
+
+	err := tb.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil
+		}
+		data, ok, err := migrateCondition(v)
+		if err != nil {
+			return err
+		}
+		if ok {
+			legacy[string(k)] = append([]byte(nil), v...)
+			migrated[string(k)] = data
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// bucket can't be changed while iterating over it
+	for k, data := range migrated {
+		// legacy condition can't be decoded, only fields used by indexes are read for removing its index entries
+		var old struct {
+			Id         uuid.UUID          `json:"id"`
+			Origin     models.TaxOrigin   `json:"origin"`
+			Categories map[uuid.UUID]bool `json:"categories"`
+		}
+		err := json.Unmarshal(legacy[k], &old)
+		if err != nil {
+			return err
+		}
+		err = unindexTax(tb, &models.Tax{Id: old.Id, Origin: old.Origin, Categories: old.Categories})
+		if err != nil {
+			return err
+		}
+
+		err = tb.Put([]byte(k), data)
+		if err != nil {
+			return err
+		}
+
+		var tax models.Tax
+		err = json.Unmarshal(data, &tax)
+		if err != nil {
+			return err
+		}
+		err = indexTax(tb, &tax)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateCondition replaces legacy condition of the encoded tax with all categories and drops its categories, which
+// legacy condition ignored. It returns false if the tax doesn't have legacy condition
+func migrateCondition(data []byte) ([]byte, bool, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var condition string
+	if v, ok := fields["condition"]; ok {
+		err = json.Unmarshal(v, &condition)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if condition != legacyCondition {
+		return data, false, nil
+	}
+
+	fields["condition"], err = json.Marshal(models.AllCategories)
+	if err != nil {
+		return nil, false, err
+	}
+	delete(fields, "categories")
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/taxes"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"go.etcd.io/bbolt"
+	"log"
+)
+
+const (
+	bucketTaxHistory = "taxes_tax_history"
+)
+
+type boltDBTaxHistoryRepository struct {
+	db *storage.BoltDB
+}
+
+func (thr *boltDBTaxHistoryRepository) init() error {
+	return thr.db.Update(func(tx *bolt.Tx) error {
+		hb, err := tx.CreateBucketIfNotExists([]byte(bucketTaxHistory))
+		if err != nil {
+			return err
+		}
+
+		// every tax has own bucket for its versions
+		var taxIds [][]byte
+		err = hb.ForEach(func(k, v []byte) error {
+			if v == nil {
+				taxIds = append(taxIds, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// versions keeping taxes with legacy condition can't be decoded, they're migrated before anything reads them
+		for _, taxId := range taxIds {
+			err = migrateVersionConditions(hb.Bucket(taxId))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// NewBoltDBTaxHistoryRepository creates tax history repository for bolt db
+func NewBoltDBTaxHistoryRepository(db *storage.BoltDB) taxes.TaxHistoryRepository {
+	thr := &boltDBTaxHistoryRepository{db}
+
+	if err := thr.init(); err != nil {
+		log.Fatalln(err)
+	}
+
+	return thr
+}
+
+// SaveTaxVersion appends version to history of the tax. Version number is assigned by repository and existing
+// versions are never overwritten.
+func (thr *boltDBTaxHistoryRepository) SaveTaxVersion(ctx context.Context, version *models.TaxVersion) (*models.TaxVersion, error) {
+	err := thr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
+		hb := tx.Bucket([]byte(bucketTaxHistory))
+
+		// every tax has own bucket for keeping versions in order
+		tb, err := hb.CreateBucketIfNotExists(version.TaxId.Bytes())
+		if err != nil {
+			return err
+		}
+
+		seq, err := tb.NextSequence()
+		if err != nil {
+			return err
+		}
+		version.Version = seq
+
+		data, err := json.Marshal(version)
+		if err != nil {
+			return err
+		}
+
+		return tb.Put(versionKey(seq), data)
+	})
+	return version, err
+}
+
+// GetTaxHistory returns versions of the tax from oldest to newest
+func (thr *boltDBTaxHistoryRepository) GetTaxHistory(ctx context.Context, taxId uuid.UUID) ([]*models.TaxVersion, error) {
+	var versions = make([]*models.TaxVersion, 0)
+	err := thr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
+		hb := tx.Bucket([]byte(bucketTaxHistory))
+
+		tb := hb.Bucket(taxId.Bytes())
+		if tb == nil {
+			return nil
+		}
+
+		return tb.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil
+			}
+			var version models.TaxVersion
+			err := json.Unmarshal(v, &version)
+			if err != nil {
+				return err
+			}
+			versions = append(versions, &version)
+			return nil
+		})
+	})
+	return versions, err
+}
+
+// versionKey returns big endian representation of version for keeping keys sorted
+func versionKey(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// migrateVersionConditions replaces legacy conditions of old and new taxes in the versions of a tax
+func migrateVersionConditions(tb *bolt.Bucket) error {
+	migrated := make(map[string][]byte)
+
+	err := tb.ForEach(func(k, v []byte) error {
+		var fields map[string]json.RawMessage
+		err := json.Unmarshal(v, &fields)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for _, key := range []string{"old", "new"} {
+			tax, ok := fields[key]
+			if !ok || string(tax) == "null" {
+				continue
+			}
+			data, ok, err := migrateCondition(tax)
+			if err != nil {
+				return err
+			}
+			if ok {
+				fields[key] = data
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		migrated[string(k)] = data
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// bucket can't be changed while iterating over it
+	for k, data := range migrated {
+		err := tb.Put([]byte(k), data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/aweris/stp/internal/models"
+	taxRepository "github.com/aweris/stp/internal/taxes/repository"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBoltDBTaxHistoryRepository_SaveTaxVersion_ShouldAssignIncreasingVersions(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+
+	tax := &models.Tax{
+		Id:     uuid.NewV1(),
+		Name:   "Test Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	v1, err := r.SaveTaxVersion(context.Background(), &models.TaxVersion{TaxId: tax.Id, Action: models.TaxActionCreated, ChangedAt: time.Now(), New: tax})
+	assert.NoError(t, err)
+
+	v2, err := r.SaveTaxVersion(context.Background(), &models.TaxVersion{TaxId: tax.Id, Action: models.TaxActionDeleted, ChangedAt: time.Now(), Old: tax})
+	assert.NoError(t, err)
+
+	assert.Equal(t, uint64(1), v1.Version)
+	assert.Equal(t, uint64(2), v2.Version)
+}
+
+func TestBoltDBTaxHistoryRepository_GetTaxHistory_ShouldReturnVersionsInOrder(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+
+	taxId := uuid.NewV1()
+
+	for _, action := range []models.TaxAction{models.TaxActionCreated, models.TaxActionUpdated, models.TaxActionDeleted} {
+		_, err := r.SaveTaxVersion(context.Background(), &models.TaxVersion{TaxId: taxId, Action: action, ChangedAt: time.Now()})
+		assert.NoError(t, err)
+	}
+
+	_, err := r.SaveTaxVersion(context.Background(), &models.TaxVersion{TaxId: uuid.NewV1(), Action: models.TaxActionCreated, ChangedAt: time.Now()})
+	assert.NoError(t, err)
+
+	list, err := r.GetTaxHistory(context.Background(), taxId)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(list))
+	assert.Equal(t, models.TaxActionCreated, list[0].Action)
+	assert.Equal(t, models.TaxActionUpdated, list[1].Action)
+	assert.Equal(t, models.TaxActionDeleted, list[2].Action)
+}
+
+func TestBoltDBTaxHistoryRepository_GetTaxHistory_WhenTaxHasNoHistory_ThenShouldReturnEmptyList(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+
+	list, err := r.GetTaxHistory(context.Background(), uuid.NewV1())
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestBoltDBTaxHistoryRepository_WhenTaxSavedWithLegacyCondition_ThenShouldReturnHistory(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+
+	// saved before conditions validated
+	tax := &models.Tax{
+		Id:        uuid.NewV1(),
+		Name:      "Test Sales Tax",
+		Rate:      decimal.NewFromFloat32(10),
+		Origin:    models.TaxOriginAll,
+		Condition: models.TaxCondition("UNKNOWN"),
+	}
+
+	_, err := r.SaveTaxVersion(context.Background(), &models.TaxVersion{TaxId: tax.Id, Action: models.TaxActionCreated, ChangedAt: time.Now(), New: tax})
+	assert.NoError(t, err)
+
+	_, err = r.SaveTaxVersion(context.Background(), &models.TaxVersion{TaxId: tax.Id, Action: models.TaxActionDeleted, ChangedAt: time.Now(), Old: tax})
+	assert.NoError(t, err)
+
+	r = taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+
+	versions, err := r.GetTaxHistory(context.Background(), tax.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(versions))
+	assert.Equal(t, models.AllCategories, versions[0].New.Condition)
+	assert.Nil(t, versions[0].Old)
+	assert.Equal(t, models.AllCategories, versions[1].Old.Condition)
+}
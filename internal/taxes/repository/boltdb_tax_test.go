@@ -2,6 +2,7 @@ package repository_test
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/aweris/stp/internal/models"
 	taxRepository "github.com/aweris/stp/internal/taxes/repository"
 	"github.com/aweris/stp/storage"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.etcd.io/bbolt"
 	"testing"
+	"time"
 )
 
 const (
@@ -97,7 +99,7 @@ func TestBoltDBTaxRepository_GetTaxesByItemOriginAndCategory_WhenItemExemptToTax
 	tax, err := r.SaveTax(context.Background(), tax)
 	assert.NoError(t, err, "failed to add tax")
 
-	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, categoryId)
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, categoryId, time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Empty(t, list)
 }
@@ -120,7 +122,7 @@ func TestBoltDBTaxRepository_GetTaxesByItemOriginAndCategory_WhenItemNotInExempt
 	tax, err := r.SaveTax(context.Background(), tax)
 	assert.NoError(t, err, "failed to add tax")
 
-	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, uuid.NewV1())
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, uuid.NewV1(), time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Equal(t, 1, len(list))
 }
@@ -145,7 +147,7 @@ func TestBoltDBTaxRepository_GetTaxesByItemOriginAndCategory_WhenItemSubjectToTa
 	tax, err := r.SaveTax(context.Background(), tax)
 	assert.NoError(t, err, "failed to add tax")
 
-	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, categoryId)
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, categoryId, time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Equal(t, 1, len(list))
 }
@@ -168,7 +170,7 @@ func TestBoltDBTaxRepository_GetTaxesByItemOriginAndCategory_WhenItemNotInSubjec
 	tax, err := r.SaveTax(context.Background(), tax)
 	assert.NoError(t, err, "failed to add tax")
 
-	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, uuid.NewV1())
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, uuid.NewV1(), time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Empty(t, list)
 }
@@ -191,7 +193,7 @@ func TestBoltDBTaxRepository_GetTaxesByItemOriginAndCategory_WhenItemOriginOk_Th
 	tax, err := r.SaveTax(context.Background(), tax)
 	assert.NoError(t, err, "failed to add tax")
 
-	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, categoryId)
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, categoryId, time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Equal(t, 1, len(list))
 }
@@ -214,7 +216,7 @@ func TestBoltDBTaxRepository_GetTaxesByItemOriginAndCategory_WhenItemOriginNotOk
 	tax, err := r.SaveTax(context.Background(), tax)
 	assert.NoError(t, err, "failed to add tax")
 
-	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, categoryId)
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, categoryId, time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Empty(t, list)
 }
@@ -273,15 +275,15 @@ func TestBoltDBTaxRepository_GetTaxesByItemOriginAndCategory(t *testing.T) {
 	exemptOnlyOne, err = r.SaveTax(context.Background(), exemptOnlyOne)
 	assert.NoError(t, err, "failed to add tax")
 
-	l1, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, c1)
+	l1, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, c1, time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Equal(t, 3, len(l1))
 
-	l2, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, c2)
+	l2, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, c2, time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Equal(t, 2, len(l2))
 
-	l3, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, c3)
+	l3, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, c3, time.Now())
 	assert.NoError(t, err, "failed to get taxes")
 	assert.Equal(t, 1, len(l3))
 }
@@ -360,3 +362,204 @@ func TestBoltDBTaxRepository_DeleteTax_WhenDeleteNonExistingId_ThanShouldNotRetu
 	assert.NoError(t, err, "failed to delete tax")
 	assert.Nil(t, deleted)
 }
+
+func TestBoltDBTaxRepository_GetTaxesByItemOriginAndCategory_WhenTaxNotValidAtGivenTime_ThanShouldReturnEmptyList(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+
+	from := time.Now().Add(time.Hour)
+
+	tax := &models.Tax{
+		Id:        uuid.NewV1(),
+		Name:      "Future Tax",
+		Rate:      decimal.NewFromFloat32(10),
+		Origin:    models.TaxOriginAll,
+		ValidFrom: &from,
+	}
+
+	tax, err := r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, uuid.NewV1(), time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Empty(t, list)
+
+	list, err = r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, uuid.NewV1(), from)
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Equal(t, 1, len(list))
+}
+
+func TestBoltDBTaxRepository_SaveTax_WhenOriginAndCategoriesChanged_ThanShouldUpdateIndexes(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+
+	oldCategory := uuid.NewV1()
+	newCategory := uuid.NewV1()
+
+	tax := &models.Tax{
+		Id:         uuid.NewV1(),
+		Name:       "Test Sales Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginLocal,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{oldCategory: true},
+	}
+
+	tax, err := r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	tax.Origin = models.TaxOriginImport
+	tax.Categories = map[uuid.UUID]bool{newCategory: true}
+
+	tax, err = r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to update tax")
+
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, oldCategory, time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Empty(t, list)
+
+	list, err = r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, oldCategory, time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Empty(t, list)
+
+	list, err = r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, newCategory, time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Equal(t, 1, len(list))
+}
+
+func TestBoltDBTaxRepository_DeleteTax_ThanShouldRemoveTaxFromLookup(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+
+	tax := &models.Tax{
+		Id:     uuid.NewV1(),
+		Name:   "Test Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	tax, err := r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	_, err = r.DeleteTax(context.Background(), tax.Id)
+	assert.NoError(t, err, "failed to delete tax")
+
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, uuid.NewV1(), time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Empty(t, list)
+}
+
+func TestBoltDBTaxRepository_WhenTaxesSavedBeforeIndexes_ThanShouldIndexExistingTaxes(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	category := uuid.NewV1()
+
+	tax := &models.Tax{
+		Id:         uuid.NewV1(),
+		Name:       "Test Sales Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginLocal,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{category: true},
+	}
+
+	err := db.BoltDB.Update(func(tx *bolt.Tx) error {
+		tb, err := tx.CreateBucketIfNotExists([]byte(bucketTax))
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(tax)
+		if err != nil {
+			return err
+		}
+		return tb.Put(tax.Id.Bytes(), data)
+	})
+	assert.NoError(t, err, "failed to add tax without index")
+
+	r := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, category, time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Equal(t, 1, len(list))
+}
+
+func TestBoltDBTaxRepository_WhenTaxSavedWithLegacyCondition_ThanShouldApplyToAllCategories(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	// saved before conditions validated
+	r := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+
+	tax := &models.Tax{
+		Id:        uuid.NewV1(),
+		Name:      "Test Sales Tax",
+		Rate:      decimal.NewFromFloat32(10),
+		Origin:    models.TaxOriginLocal,
+		Condition: models.TaxCondition("UNKNOWN"),
+	}
+
+	tax, err := r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	_, err = r.FetchAllTaxes(context.Background())
+	assert.Error(t, err)
+
+	r = taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+
+	list, err := r.FetchAllTaxes(context.Background())
+	assert.NoError(t, err, "failed to fetch taxes")
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, models.AllCategories, list[0].Condition)
+
+	list, err = r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, uuid.NewV1(), time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Equal(t, 1, len(list))
+}
+
+func TestBoltDBTaxRepository_WhenLegacyTaxListsCategories_ThanShouldDropCategoriesAndKeepTaxUpdatable(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	// saved before conditions validated
+	r := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+
+	category := uuid.NewV1()
+
+	tax := &models.Tax{
+		Id:         uuid.NewV1(),
+		Name:       "Test Sales Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginLocal,
+		Condition:  models.TaxCondition("UNKNOWN"),
+		Categories: map[uuid.UUID]bool{category: true},
+	}
+
+	_, err := r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	r = taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+
+	migrated, err := r.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AllCategories, migrated.Condition)
+	assert.Empty(t, migrated.Categories)
+
+	migrated.Rate = decimal.NewFromFloat32(12)
+
+	_, err = r.SaveTax(context.Background(), migrated)
+	assert.NoError(t, err, "failed to update migrated tax")
+
+	for _, categoryId := range []uuid.UUID{category, uuid.NewV1()} {
+		list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, categoryId, time.Now())
+		assert.NoError(t, err, "failed to get taxes")
+		assert.Equal(t, 1, len(list))
+		assert.True(t, list[0].Rate.Equal(decimal.NewFromFloat32(12)))
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/taxes"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"go.etcd.io/bbolt"
+	"log"
+)
+
+const (
+	bucketZone = "taxes_zone"
+)
+
+type boltDBZoneRepository struct {
+	db *storage.BoltDB
+}
+
+func (zr *boltDBZoneRepository) init() error {
+	return zr.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketZone))
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// NewBoltDBZoneRepository creates zone repository for bolt db
+func NewBoltDBZoneRepository(db *storage.BoltDB) taxes.ZoneRepository {
+	zr := &boltDBZoneRepository{db}
+
+	if err := zr.init(); err != nil {
+		log.Fatalln(err)
+	}
+
+	return zr
+}
+
+func (zr *boltDBZoneRepository) SaveZone(ctx context.Context, zone *models.Zone) (*models.Zone, error) {
+	err := zr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
+		zb := tx.Bucket([]byte(bucketZone))
+
+		data, err := json.Marshal(zone)
+		if err != nil {
+			return err
+		}
+
+		return zb.Put(zone.Id.Bytes(), data)
+	})
+	return zone, err
+}
+
+func (zr *boltDBZoneRepository) GetZoneByID(ctx context.Context, zoneId uuid.UUID) (*models.Zone, error) {
+	var zone *models.Zone
+	err := zr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
+		zb := tx.Bucket([]byte(bucketZone))
+
+		v := zb.Get(zoneId.Bytes())
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &zone)
+	})
+	return zone, err
+}
+
+func (zr *boltDBZoneRepository) FetchAllZones(ctx context.Context) ([]*models.Zone, error) {
+	var zones = make([]*models.Zone, 0)
+	err := zr.db.ViewContext(ctx, func(tx *bolt.Tx) error {
+		zb := tx.Bucket([]byte(bucketZone))
+
+		return zb.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil
+			}
+			var zone models.Zone
+			err := json.Unmarshal(v, &zone)
+			if err != nil {
+				return err
+			}
+			zones = append(zones, &zone)
+			return nil
+		})
+	})
+	return zones, err
+}
+
+func (zr *boltDBZoneRepository) DeleteZone(ctx context.Context, zoneId uuid.UUID) (*models.Zone, error) {
+	var existing *models.Zone
+	err := zr.db.UpdateContext(ctx, func(tx *bolt.Tx) error {
+		zb := tx.Bucket([]byte(bucketZone))
+
+		v := zb.Get(zoneId.Bytes())
+		if v == nil {
+			return nil
+		}
+		err := json.Unmarshal(v, &existing)
+		if err != nil {
+			return err
+		}
+
+		return zb.Delete(zoneId.Bytes())
+	})
+	return existing, err
+}
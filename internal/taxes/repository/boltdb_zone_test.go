@@ -0,0 +1,51 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/aweris/stp/internal/models"
+	taxRepository "github.com/aweris/stp/internal/taxes/repository"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBoltDBZoneRepository_SaveZone_ThanShouldGetZoneByID(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBZoneRepository(db.BoltDB)
+
+	zone := &models.Zone{Id: uuid.NewV1(), Name: "Ontario"}
+
+	_, err := r.SaveZone(context.Background(), zone)
+	assert.NoError(t, err, "failed to add zone")
+
+	find, err := r.GetZoneByID(context.Background(), zone.Id)
+	assert.NoError(t, err, "failed to get zone")
+	assert.Equal(t, zone, find)
+
+	list, err := r.FetchAllZones(context.Background())
+	assert.NoError(t, err, "failed to fetch zones")
+	assert.Equal(t, 1, len(list))
+}
+
+func TestBoltDBZoneRepository_DeleteZone_ThanShouldDeleteZoneAndReturnDeletedZone(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewBoltDBZoneRepository(db.BoltDB)
+
+	zone := &models.Zone{Id: uuid.NewV1(), Name: "Ontario"}
+
+	_, err := r.SaveZone(context.Background(), zone)
+	assert.NoError(t, err, "failed to add zone")
+
+	deleted, err := r.DeleteZone(context.Background(), zone.Id)
+	assert.NoError(t, err, "failed to delete zone")
+	assert.Equal(t, zone, deleted)
+
+	find, err := r.GetZoneByID(context.Background(), zone.Id)
+	assert.NoError(t, err, "failed to get zone")
+	assert.Nil(t, find)
+}
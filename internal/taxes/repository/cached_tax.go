@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/taxes"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"sync"
+	"time"
+)
+
+type cachedTaxRepository struct {
+	repo taxes.TaxRepository
+
+	mu       sync.RWMutex
+	snapshot *taxSnapshot
+}
+
+// taxSnapshot is an immutable in memory copy of all taxes, replaced as a whole after each write
+type taxSnapshot struct {
+	all  []*models.Tax
+	byId map[uuid.UUID]*models.Tax
+	// taxes grouped by origin, lookup only needs to check item origin and ALL
+	byOrigin map[models.TaxOrigin][]*models.Tax
+}
+
+// NewCachedTaxRepository wraps given tax repository and keeps all taxes in memory until a tax is saved or deleted.
+// Calls made in a transaction go to the wrapped repository, so they see changes not committed yet.
+func NewCachedTaxRepository(repo taxes.TaxRepository) taxes.TaxRepository {
+	return &cachedTaxRepository{repo: repo}
+}
+
+func (ctr *cachedTaxRepository) SaveTax(ctx context.Context, tax *models.Tax) (*models.Tax, error) {
+	defer storage.AfterCommit(ctx, ctr.invalidate)
+
+	return ctr.repo.SaveTax(ctx, tax)
+}
+
+func (ctr *cachedTaxRepository) GetTaxByID(ctx context.Context, taxId uuid.UUID) (*models.Tax, error) {
+	if storage.InTransaction(ctx) {
+		return ctr.repo.GetTaxByID(ctx, taxId)
+	}
+
+	s, err := ctr.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tax, ok := s.byId[taxId]
+	if !ok {
+		return nil, nil
+	}
+	return copyTax(tax), nil
+}
+
+func (ctr *cachedTaxRepository) GetTaxesByItemOriginAndCategory(ctx context.Context, origin models.ItemOrigin, categoryId uuid.UUID, at time.Time) ([]*models.Tax, error) {
+	if storage.InTransaction(ctx) {
+		return ctr.repo.GetTaxesByItemOriginAndCategory(ctx, origin, categoryId, at)
+	}
+
+	s, err := ctr.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	origins := []models.TaxOrigin{models.TaxOrigin(origin)}
+	if origins[0] != models.TaxOriginAll {
+		origins = append(origins, models.TaxOriginAll)
+	}
+
+	var txs = make([]*models.Tax, 0)
+	for _, o := range origins {
+		for _, tax := range s.byOrigin[o] {
+			if tax.IsValidAt(at) && tax.AppliesTo(origin, categoryId) {
+				txs = append(txs, copyTax(tax))
+			}
+		}
+	}
+	return txs, nil
+}
+
+func (ctr *cachedTaxRepository) FetchAllTaxes(ctx context.Context) ([]*models.Tax, error) {
+	if storage.InTransaction(ctx) {
+		return ctr.repo.FetchAllTaxes(ctx)
+	}
+
+	s, err := ctr.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var txs = make([]*models.Tax, 0, len(s.all))
+	for _, tax := range s.all {
+		txs = append(txs, copyTax(tax))
+	}
+	return txs, nil
+}
+
+func (ctr *cachedTaxRepository) DeleteTax(ctx context.Context, taxId uuid.UUID) (*models.Tax, error) {
+	defer storage.AfterCommit(ctx, ctr.invalidate)
+
+	return ctr.repo.DeleteTax(ctx, taxId)
+}
+
+// load returns cached taxes, fills the cache from underlying repository if it is not loaded yet
+func (ctr *cachedTaxRepository) load(ctx context.Context) (*taxSnapshot, error) {
+	ctr.mu.RLock()
+	s := ctr.snapshot
+	ctr.mu.RUnlock()
+
+	if s != nil {
+		return s, nil
+	}
+
+	ctr.mu.Lock()
+	defer ctr.mu.Unlock()
+
+	// another goroutine may load the cache while waiting for the lock
+	if ctr.snapshot != nil {
+		return ctr.snapshot, nil
+	}
+
+	all, err := ctr.repo.FetchAllTaxes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s = &taxSnapshot{
+		all:      all,
+		byId:     make(map[uuid.UUID]*models.Tax, len(all)),
+		byOrigin: make(map[models.TaxOrigin][]*models.Tax),
+	}
+
+	for _, tax := range all {
+		s.byId[tax.Id] = tax
+		s.byOrigin[tax.Origin] = append(s.byOrigin[tax.Origin], tax)
+	}
+
+	ctr.snapshot = s
+	return s, nil
+}
+
+// invalidate drops cached taxes, next read loads them again from underlying repository. Writes made in a transaction
+// drop them after commit, otherwise a read before commit could cache the old taxes again.
+func (ctr *cachedTaxRepository) invalidate() {
+	ctr.mu.Lock()
+	defer ctr.mu.Unlock()
+
+	ctr.snapshot = nil
+}
+
+// copyTax returns a deep copy of cached tax, so callers modifying the result don't change the cache
+func copyTax(tax *models.Tax) *models.Tax {
+	t := *tax
+
+	if tax.Categories != nil {
+		t.Categories = make(map[uuid.UUID]bool, len(tax.Categories))
+		for categoryId, listed := range tax.Categories {
+			t.Categories[categoryId] = listed
+		}
+	}
+	if tax.Zones != nil {
+		t.Zones = append([]uuid.UUID(nil), tax.Zones...)
+	}
+	if tax.Rounding != nil {
+		rounding := *tax.Rounding
+		t.Rounding = &rounding
+	}
+	if tax.ValidFrom != nil {
+		from := *tax.ValidFrom
+		t.ValidFrom = &from
+	}
+	if tax.ValidTo != nil {
+		to := *tax.ValidTo
+		t.ValidTo = &to
+	}
+	t.Rule = copyTaxRule(tax.Rule)
+
+	return &t
+}
+
+// copyTaxRule returns a deep copy of the rule with its sub rules
+func copyTaxRule(rule *models.TaxRule) *models.TaxRule {
+	if rule == nil {
+		return nil
+	}
+
+	r := *rule
+
+	if rule.Rules != nil {
+		r.Rules = make([]*models.TaxRule, 0, len(rule.Rules))
+		for _, sub := range rule.Rules {
+			r.Rules = append(r.Rules, copyTaxRule(sub))
+		}
+	}
+	if rule.Amount != nil {
+		amount := *rule.Amount
+		r.Amount = &amount
+	}
+	if rule.Items != nil {
+		r.Items = append([]uuid.UUID(nil), rule.Items...)
+	}
+	if rule.Tags != nil {
+		r.Tags = append([]string(nil), rule.Tags...)
+	}
+	return &r
+}
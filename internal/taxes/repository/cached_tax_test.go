@@ -0,0 +1,234 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/aweris/stp/internal/models"
+	"github.com/aweris/stp/internal/taxes"
+	taxRepository "github.com/aweris/stp/internal/taxes/repository"
+	"github.com/aweris/stp/storage"
+	"github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedTaxRepository_SaveTax_ThanShouldInvalidateCache(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewCachedTaxRepository(taxRepository.NewBoltDBTaxRepository(db.BoltDB))
+
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, uuid.NewV1(), time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Empty(t, list)
+
+	tax := &models.Tax{
+		Id:     uuid.NewV1(),
+		Name:   "Test Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	tax, err = r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	list, err = r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, uuid.NewV1(), time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Equal(t, 1, len(list))
+
+	tax.Rate = decimal.NewFromFloat32(20)
+
+	_, err = r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to update tax")
+
+	find, err := r.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err, "failed to get tax")
+	assert.True(t, decimal.NewFromFloat32(20).Equal(find.Rate))
+}
+
+func TestCachedTaxRepository_DeleteTax_ThanShouldInvalidateCache(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewCachedTaxRepository(taxRepository.NewBoltDBTaxRepository(db.BoltDB))
+
+	tax := &models.Tax{
+		Id:     uuid.NewV1(),
+		Name:   "Test Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	tax, err := r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	list, err := r.FetchAllTaxes(context.Background())
+	assert.NoError(t, err, "failed to fetch taxes")
+	assert.Equal(t, 1, len(list))
+
+	_, err = r.DeleteTax(context.Background(), tax.Id)
+	assert.NoError(t, err, "failed to delete tax")
+
+	find, err := r.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err, "failed to get tax")
+	assert.Nil(t, find)
+}
+
+func TestCachedTaxRepository_SaveTax_WhenInTransaction_ThanShouldInvalidateCacheAfterCommit(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewCachedTaxRepository(taxRepository.NewBoltDBTaxRepository(db.BoltDB))
+
+	tax := &models.Tax{
+		Id:     uuid.NewV1(),
+		Name:   "Test Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	err := db.RunInTransaction(context.Background(), func(ctx context.Context) error {
+		_, err := r.SaveTax(ctx, tax)
+		assert.NoError(t, err, "failed to add tax")
+
+		// loads cache before the tax committed
+		list, err := r.FetchAllTaxes(context.Background())
+		assert.NoError(t, err, "failed to fetch taxes")
+		assert.Empty(t, list)
+
+		// reads in transaction see the tax not committed yet
+		find, err := r.GetTaxByID(ctx, tax.Id)
+		assert.NoError(t, err, "failed to get tax")
+		assert.NotNil(t, find)
+		return err
+	})
+	assert.NoError(t, err, "failed to commit transaction")
+
+	find, err := r.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err, "failed to get tax")
+	assert.NotNil(t, find)
+}
+
+func TestCachedTaxRepository_GetTaxByID_WhenResultModified_ThanShouldNotChangeCache(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewCachedTaxRepository(taxRepository.NewBoltDBTaxRepository(db.BoltDB))
+
+	categoryId := uuid.NewV1()
+	zoneId := uuid.NewV1()
+	to := time.Now().Add(time.Hour)
+	amount := decimal.NewFromFloat32(100)
+
+	tax := &models.Tax{
+		Id:         uuid.NewV1(),
+		Name:       "Test Sales Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{categoryId: true},
+		Zones:      []uuid.UUID{zoneId},
+		Rounding:   &models.RoundingRule{Increment: decimal.New(5, -2), Mode: models.RoundingModeCeil},
+		Rule:       &models.TaxRule{Type: models.TaxRuleNot, Rules: []*models.TaxRule{{Type: models.TaxRulePriceAbove, Amount: &amount}}},
+		ValidTo:    &to,
+	}
+
+	tax, err := r.SaveTax(context.Background(), tax)
+	assert.NoError(t, err, "failed to add tax")
+
+	find, err := r.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err, "failed to get tax")
+
+	find.Rate = decimal.NewFromFloat32(50)
+	find.Categories[categoryId] = false
+	find.Categories[uuid.NewV1()] = true
+	find.Zones[0] = uuid.NewV1()
+	find.Rounding.Mode = models.RoundingModeFloor
+	find.Rule.Rules[0].Amount = nil
+	*find.ValidTo = to.Add(time.Hour)
+
+	find, err = r.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err, "failed to get tax")
+	assert.True(t, decimal.NewFromFloat32(10).Equal(find.Rate))
+	assert.Equal(t, map[uuid.UUID]bool{categoryId: true}, find.Categories)
+	assert.Equal(t, []uuid.UUID{zoneId}, find.Zones)
+	assert.Equal(t, models.RoundingModeCeil, find.Rounding.Mode)
+	assert.NotNil(t, find.Rule.Rules[0].Amount)
+	assert.True(t, to.Equal(*find.ValidTo))
+}
+
+func TestCachedTaxRepository_WhenUsedConcurrently_ThanShouldSeeAllTaxes(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	r := taxRepository.NewCachedTaxRepository(taxRepository.NewBoltDBTaxRepository(db.BoltDB))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := r.SaveTax(context.Background(), &models.Tax{
+				Id:     uuid.NewV1(),
+				Name:   "Test Sales Tax",
+				Rate:   decimal.NewFromFloat32(10),
+				Origin: models.TaxOriginAll,
+			})
+			assert.NoError(t, err, "failed to add tax")
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, uuid.NewV1(), time.Now())
+			assert.NoError(t, err, "failed to get taxes")
+		}()
+	}
+	wg.Wait()
+
+	list, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginLocal, uuid.NewV1(), time.Now())
+	assert.NoError(t, err, "failed to get taxes")
+	assert.Equal(t, 10, len(list))
+}
+
+func BenchmarkBoltDBTaxRepository_GetTaxesByItemOriginAndCategory(b *testing.B) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	benchmarkGetTaxesByItemOriginAndCategory(b, taxRepository.NewBoltDBTaxRepository(db.BoltDB))
+}
+
+func BenchmarkCachedTaxRepository_GetTaxesByItemOriginAndCategory(b *testing.B) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	benchmarkGetTaxesByItemOriginAndCategory(b, taxRepository.NewCachedTaxRepository(taxRepository.NewBoltDBTaxRepository(db.BoltDB)))
+}
+
+// benchmarkGetTaxesByItemOriginAndCategory looks up taxes for an item among few hundred regional taxes
+func benchmarkGetTaxesByItemOriginAndCategory(b *testing.B, r taxes.TaxRepository) {
+	category := uuid.NewV1()
+	origins := []models.TaxOrigin{models.TaxOriginAll, models.TaxOriginLocal, models.TaxOriginImport}
+
+	for i := 0; i < 300; i++ {
+		_, err := r.SaveTax(context.Background(), &models.Tax{
+			Id:         uuid.NewV1(),
+			Name:       "Regional Sales Tax",
+			Rate:       decimal.NewFromFloat32(10),
+			Origin:     origins[i%len(origins)],
+			Condition:  models.SubjectToTax,
+			Categories: map[uuid.UUID]bool{uuid.NewV1(): true, category: i%10 == 0},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := r.GetTaxesByItemOriginAndCategory(context.Background(), models.ItemOriginImported, category, time.Now())
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/aweris/stp/internal/models"
 	"github.com/satori/go.uuid"
+	"github.com/shopspring/decimal"
+	"time"
 )
 
 type TaxService interface {
@@ -12,5 +14,14 @@ type TaxService interface {
 	GetTaxByID(ctx context.Context, taxId uuid.UUID) (*models.Tax, error)
 	FetchAllTaxes(ctx context.Context) ([]*models.Tax, error)
 	DeleteTax(ctx context.Context, taxId uuid.UUID) (*models.Tax, error)
-	GetSaleItem(ctx context.Context, item *models.InventoryItem) (*models.SaleItem, error)
+	GetTaxHistory(ctx context.Context, taxId uuid.UUID) ([]*models.TaxVersion, error)
+	ScheduleRateChange(ctx context.Context, taxId uuid.UUID, rate decimal.Decimal, from time.Time) (*models.Tax, error)
+	GetSaleItem(ctx context.Context, item *models.InventoryItem, scope models.TaxScope) (*models.SaleItem, error)
+	FindConflicts(ctx context.Context) ([]*models.TaxConflict, error)
+
+	CreateZone(ctx context.Context, zone *models.Zone) (*models.Zone, error)
+	UpdateZone(ctx context.Context, zone *models.Zone) (*models.Zone, error)
+	GetZoneByID(ctx context.Context, zoneId uuid.UUID) (*models.Zone, error)
+	FetchAllZones(ctx context.Context) ([]*models.Zone, error)
+	DeleteZone(ctx context.Context, zoneId uuid.UUID) (*models.Zone, error)
 }
\ No newline at end of file
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"github.com/aweris/stp/internal/models"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+var (
+	hundred = decimal.NewFromFloat32(100)
+)
+
+// calculator calculates tax lines of given taxes levied on the same base
+type calculator func(base decimal.Decimal, txs []*models.Tax) []*models.TaxLine
+
+// netPrice back-calculates price without taxes from a tax inclusive price
+func netPrice(price decimal.Decimal, txs []*models.Tax) decimal.Decimal {
+	// price with taxes applied so far is net * factor + fixed
+	factor := decimal.New(1, 0)
+	fixed := decimal.Zero
+
+	for _, step := range steps(txs) {
+		plain, compound, fixedTaxes := splitStep(step)
+
+		compoundRate := sumRates(compound).Div(hundred)
+
+		factor = factor.Add(sumRates(plain).Div(hundred)).Add(factor.Mul(compoundRate))
+		fixed = fixed.Add(fixed.Mul(compoundRate)).Add(sumAmounts(fixedTaxes))
+	}
+
+	return price.Sub(fixed).Div(factor)
+}
+
+// calculateInSequence applies taxes in priority order. Non compound taxes levied on price, compound taxes levied on
+// price plus taxes of previous priorities and fixed taxes add their amount as is.
+func calculateInSequence(price decimal.Decimal, txs []*models.Tax, calc calculator) []*models.TaxLine {
+	lines := make([]*models.TaxLine, 0, len(txs))
+	levied := decimal.Zero
+
+	for _, step := range steps(txs) {
+		plain, compound, fixed := splitStep(step)
+
+		stepLines := append(calc(price, plain), calc(price.Add(levied), compound)...)
+		stepLines = append(stepLines, calculateFixed(price, fixed)...)
+
+		for _, line := range stepLines {
+			levied = levied.Add(line.Amount)
+		}
+
+		lines = append(lines, stepLines...)
+	}
+
+	return lines
+}
+
+// steps groups taxes with same priority, ordered by ascending priority
+func steps(txs []*models.Tax) [][]*models.Tax {
+	sorted := make([]*models.Tax, len(txs))
+	copy(sorted, txs)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	result := make([][]*models.Tax, 0)
+
+	for i := 0; i < len(sorted); {
+		j := i
+		for j < len(sorted) && sorted[j].Priority == sorted[i].Priority {
+			j++
+		}
+		result = append(result, sorted[i:j])
+		i = j
+	}
+
+	return result
+}
+
+// splitStep splits taxes of a step into non compound percentage, compound percentage and fixed taxes
+func splitStep(txs []*models.Tax) (plain []*models.Tax, compound []*models.Tax, fixed []*models.Tax) {
+	for _, tax := range txs {
+		switch {
+		case tax.IsFixed():
+			fixed = append(fixed, tax)
+		case tax.Compound:
+			compound = append(compound, tax)
+		default:
+			plain = append(plain, tax)
+		}
+	}
+	return plain, compound, fixed
+}
+
+func sumRates(txs []*models.Tax) decimal.Decimal {
+	rate := decimal.Zero
+
+	for _, tax := range txs {
+		rate = rate.Add(tax.Rate)
+	}
+
+	return rate
+}
+
+func sumAmounts(txs []*models.Tax) decimal.Decimal {
+	amount := decimal.Zero
+
+	for _, tax := range txs {
+		amount = amount.Add(tax.Amount)
+	}
+
+	return amount
+}
+
+// calculateFixed adds amount of each fixed tax without rounding
+func calculateFixed(price decimal.Decimal, txs []*models.Tax) []*models.TaxLine {
+	lines := make([]*models.TaxLine, 0, len(txs))
+
+	for _, tax := range txs {
+		lines = append(lines, newTaxLine(tax, price, tax.Amount))
+	}
+
+	return lines
+}
+
+// calculatePerTax calculates and rounds each tax individually
+func calculatePerTax(price decimal.Decimal, txs []*models.Tax) []*models.TaxLine {
+	lines := make([]*models.TaxLine, 0, len(txs))
+
+	for _, tax := range txs {
+		amount := tax.EffectiveRounding().Round(price.Mul(tax.Rate).Div(hundred))
+
+		lines = append(lines, newTaxLine(tax, price, amount))
+	}
+
+	return lines
+}
+
+// calculateCombined sums rates of taxes sharing same rounding rule and rounds once for each rule. Rounded amount
+// distributed to the taxes proportionally to their rates.
+func calculateCombined(price decimal.Decimal, txs []*models.Tax) []*models.TaxLine {
+	rules := make([]*models.RoundingRule, 0)
+	groups := make([][]*models.Tax, 0)
+
+	for _, tax := range txs {
+		rule := tax.EffectiveRounding()
+
+		i := 0
+		for i < len(rules) && !rules[i].Equal(rule) {
+			i++
+		}
+		if i == len(rules) {
+			rules = append(rules, rule)
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], tax)
+	}
+
+	lines := make([]*models.TaxLine, 0, len(txs))
+
+	for i, rule := range rules {
+		group := groups[i]
+
+		rate := sumRates(group)
+
+		amount := rule.Round(price.Mul(rate).Div(hundred))
+
+		lines = append(lines, allocate(price, amount, rate, group, rule)...)
+	}
+
+	return lines
+}
+
+// allocate distributes amount to the taxes proportionally to their rates, last tax takes the remainder.
+func allocate(base decimal.Decimal, amount decimal.Decimal, rate decimal.Decimal, txs []*models.Tax, rule *models.RoundingRule) []*models.TaxLine {
+	places := -rule.Increment.Exponent()
+	if places < 0 {
+		places = 0
+	}
+
+	lines := make([]*models.TaxLine, 0, len(txs))
+	remainder := amount
+
+	for i, tax := range txs {
+		share := remainder
+		if i < len(txs)-1 {
+			share = amount.Mul(tax.Rate).Div(rate).Round(places)
+		}
+		remainder = remainder.Sub(share)
+
+		lines = append(lines, newTaxLine(tax, base, share))
+	}
+
+	return lines
+}
+
+func newTaxLine(tax *models.Tax, base decimal.Decimal, amount decimal.Decimal) *models.TaxLine {
+	return &models.TaxLine{
+		TaxId:    tax.Id,
+		Name:     tax.Name,
+		Kind:     tax.Kind,
+		Rate:     tax.Rate,
+		Rounding: tax.EffectiveRounding(),
+		Priority: tax.Priority,
+		Compound: tax.Compound,
+		Base:     base,
+		Amount:   amount,
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"github.com/aweris/stp/internal/models"
+	"github.com/satori/go.uuid"
+	"strings"
+	"time"
+)
+
+// conflictBetween checks two taxes would be charged together for the same sale. Taxes with the same name or coverage
+// are likely duplicates of each other, others, like basic sales tax and import duty, only overlap.
+func conflictBetween(a *models.Tax, b *models.Tax) (models.TaxConflictReason, bool) {
+	if uuid.Equal(a.Id, b.Id) || !overlaps(a, b) {
+		return "", false
+	}
+
+	if strings.EqualFold(strings.TrimSpace(a.Name), strings.TrimSpace(b.Name)) {
+		return models.TaxConflictSameName, true
+	}
+
+	if sameCoverage(a, b) {
+		return models.TaxConflictSameCoverage, true
+	}
+
+	return models.TaxConflictOverlap, true
+}
+
+// rejected checks taxes conflicting for the reason can't be saved together. Overlaps are charged together on purpose,
+// so they are only reported.
+func rejected(reason models.TaxConflictReason) bool {
+	return reason != models.TaxConflictOverlap
+}
+
+// overlaps checks there is an item, zone and time both taxes applies to. Tax rules are not evaluated, so taxes
+// narrowed down with rules may still be reported as overlapping.
+func overlaps(a *models.Tax, b *models.Tax) bool {
+	return originsOverlap(a, b) && categoriesOverlap(a, b) && zonesOverlap(a, b) && validitiesOverlap(a, b)
+}
+
+func originsOverlap(a *models.Tax, b *models.Tax) bool {
+	return a.Origin == b.Origin || a.Origin == models.TaxOriginAll || b.Origin == models.TaxOriginAll
+}
+
+func categoriesOverlap(a *models.Tax, b *models.Tax) bool {
+	switch {
+	case a.Condition == models.SubjectToTax && b.Condition == models.SubjectToTax:
+		for id, listed := range a.Categories {
+			if listed && b.Categories[id] {
+				return true
+			}
+		}
+		return false
+	case a.Condition == models.SubjectToTax:
+		return coversAny(a, b)
+	case b.Condition == models.SubjectToTax:
+		return coversAny(b, a)
+	default:
+		// exempt and all categories conditions covers infinitely many categories
+		return true
+	}
+}
+
+// coversAny checks any category subject to tax is also covered by other tax
+func coversAny(subject *models.Tax, other *models.Tax) bool {
+	for id, listed := range subject.Categories {
+		if listed && (other.Condition != models.ExemptToTax || !other.Categories[id]) {
+			return true
+		}
+	}
+	return false
+}
+
+func zonesOverlap(a *models.Tax, b *models.Tax) bool {
+	if len(a.Zones) == 0 || len(b.Zones) == 0 {
+		return true
+	}
+
+	for _, id := range a.Zones {
+		if b.AppliesInZone(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func validitiesOverlap(a *models.Tax, b *models.Tax) bool {
+	return startsBefore(a.ValidFrom, b.ValidTo) && startsBefore(b.ValidFrom, a.ValidTo)
+}
+
+// startsBefore checks validity starting from given time starts before the end of other validity
+func startsBefore(from *time.Time, to *time.Time) bool {
+	return from == nil || to == nil || from.Before(*to)
+}
+
+// sameCoverage checks taxes charge the same on the same items in the same zones at the same step. Taxes with
+// different charges or stacked at different steps, like federal and provincial taxes, are levied together on purpose.
+func sameCoverage(a *models.Tax, b *models.Tax) bool {
+	return a.Origin == b.Origin && a.Condition == b.Condition && sameCategories(a, b) && sameZones(a, b) &&
+		a.Priority == b.Priority && a.Compound == b.Compound && sameCharge(a, b)
+}
+
+func sameCharge(a *models.Tax, b *models.Tax) bool {
+	if a.IsFixed() != b.IsFixed() {
+		return false
+	}
+	if a.IsFixed() {
+		return a.Amount.Equal(b.Amount)
+	}
+	return a.Rate.Equal(b.Rate)
+}
+
+func sameZones(a *models.Tax, b *models.Tax) bool {
+	if len(a.Zones) != len(b.Zones) {
+		return false
+	}
+	for _, id := range a.Zones {
+		if !b.AppliesInZone(id) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameCategories(a *models.Tax, b *models.Tax) bool {
+	for id, listed := range a.Categories {
+		if listed != b.Categories[id] {
+			return false
+		}
+	}
+	for id, listed := range b.Categories {
+		if listed != a.Categories[id] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,25 +2,31 @@ package service
 
 import (
 	"context"
+	"github.com/aweris/stp/internal/audit"
+	"github.com/aweris/stp/internal/inventory"
 	"github.com/aweris/stp/internal/models"
 	"github.com/aweris/stp/internal/taxes"
 	"github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
-)
-
-var (
-	twenty  = decimal.NewFromFloat32(20)
-	hundred = decimal.NewFromFloat32(100)
+	"time"
 )
 
 type taxService struct {
-	taxRepo taxes.TaxRepository
+	taxRepo     taxes.TaxRepository
+	historyRepo taxes.TaxHistoryRepository
+	zoneRepo    taxes.ZoneRepository
+
+	categoryRepo inventory.CategoryRepository
+
+	transactor taxes.Transactor
+
+	mode models.TaxCalculationMode
 }
 
-// NewTaxService creates inventory service with given repository interfaces
-func NewTaxService(taxRepo taxes.TaxRepository) taxes.TaxService {
-	return &taxService{taxRepo: taxRepo}
+// NewTaxService creates tax service with given repository interfaces, transactor and tax calculation mode
+func NewTaxService(taxRepo taxes.TaxRepository, historyRepo taxes.TaxHistoryRepository, zoneRepo taxes.ZoneRepository, categoryRepo inventory.CategoryRepository, transactor taxes.Transactor, mode models.TaxCalculationMode) taxes.TaxService {
+	return &taxService{taxRepo: taxRepo, historyRepo: historyRepo, zoneRepo: zoneRepo, categoryRepo: categoryRepo, transactor: transactor, mode: mode}
 }
 
 func (ts *taxService) CreateTax(ctx context.Context, tax *models.Tax) (*models.Tax, error) {
@@ -28,30 +34,52 @@ func (ts *taxService) CreateTax(ctx context.Context, tax *models.Tax) (*models.T
 		log.WithError(taxes.ErrInvalidParameter).Error("missing tax")
 		return nil, taxes.ErrInvalidParameter
 	}
-	if tax.Name == "" {
-		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxName).Error("missing tax name")
-		return nil, taxes.ErrInvalidTaxName
-	}
-	if !tax.Rate.IsPositive() {
-		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxRate).Error("invalid tax rate")
-		return nil, taxes.ErrInvalidTaxRate
+	if err := validateTax(tax); err != nil {
+		return nil, err
 	}
+	var nt *models.Tax
 
-	if tax.Id != uuid.Nil {
-		exist, err := ts.taxRepo.GetTaxByID(ctx, tax.Id)
-		if err != nil {
-			log.WithFields(log.Fields{"tax": tax}).WithError(err).Error("failed to check existing taxes with given id")
-			return nil, err
+	// zones and categories checked in the same transaction, so they can't be deleted before tax saved. Tax and its
+	// history version committed together.
+	err := ts.transactor.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := ts.checkZones(ctx, tax); err != nil {
+			return err
 		}
-		if exist != nil {
-			log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxId).Error("tax find with given id")
-			return nil, taxes.ErrInvalidTaxId
+		if err := ts.checkCategories(ctx, tax); err != nil {
+			return err
+		}
+
+		if tax.Id != uuid.Nil {
+			exist, err := ts.taxRepo.GetTaxByID(ctx, tax.Id)
+			if err != nil {
+				log.WithFields(log.Fields{"tax": tax}).WithError(err).Error("failed to check existing taxes with given id")
+				return err
+			}
+			if exist != nil {
+				log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxId).Error("tax find with given id")
+				return taxes.ErrInvalidTaxId
+			}
+		} else {
+			tax.Id = uuid.NewV1()
 		}
-	} else {
-		tax.Id = uuid.NewV1()
-	}
 
-	return ts.taxRepo.SaveTax(ctx, tax)
+		if err := ts.checkConflicts(ctx, tax); err != nil {
+			return err
+		}
+
+		var err error
+		nt, err = ts.taxRepo.SaveTax(ctx, tax)
+		if err != nil {
+			log.WithFields(log.Fields{"tax": tax}).WithError(err).Error("failed to create tax")
+			return err
+		}
+
+		return ts.recordVersion(ctx, models.TaxActionCreated, nil, nt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nt, nil
 }
 
 func (ts *taxService) UpdateTax(ctx context.Context, tax *models.Tax) (*models.Tax, error) {
@@ -62,25 +90,46 @@ func (ts *taxService) UpdateTax(ctx context.Context, tax *models.Tax) (*models.T
 		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxId).Error("missing tax id")
 		return nil, taxes.ErrInvalidTaxId
 	}
-	if tax.Name == "" {
-		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxName).Error("missing tax name")
-		return nil, taxes.ErrInvalidTaxName
-	}
-	if !tax.Rate.IsPositive() {
-		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxRate).Error("missing tax rate")
-		return nil, taxes.ErrInvalidTaxRate
+	if err := validateTax(tax); err != nil {
+		return nil, err
 	}
+	var nt *models.Tax
+
+	// zones and categories checked in the same transaction, so they can't be deleted before tax saved. Tax and its
+	// history version committed together.
+	err := ts.transactor.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := ts.checkZones(ctx, tax); err != nil {
+			return err
+		}
+		if err := ts.checkCategories(ctx, tax); err != nil {
+			return err
+		}
+
+		exist, err := ts.taxRepo.GetTaxByID(ctx, tax.Id)
+		if err != nil {
+			return err
+		}
+		if exist == nil {
+			log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxId).Error("failed to find tax with given id")
+			return taxes.ErrInvalidTaxId
+		}
+
+		if err := ts.checkConflicts(ctx, tax); err != nil {
+			return err
+		}
 
-	exist, err := ts.taxRepo.GetTaxByID(ctx, tax.Id)
+		nt, err = ts.taxRepo.SaveTax(ctx, tax)
+		if err != nil {
+			log.WithFields(log.Fields{"tax": tax}).WithError(err).Error("failed to update tax")
+			return err
+		}
+
+		return ts.recordVersion(ctx, models.TaxActionUpdated, exist, nt)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if exist == nil {
-		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxId).Error("failed to find tax with given id")
-		return nil, taxes.ErrInvalidTaxId
-	}
-
-	return ts.taxRepo.SaveTax(ctx, tax)
+	return nt, nil
 }
 
 func (ts *taxService) GetTaxByID(ctx context.Context, taxId uuid.UUID) (*models.Tax, error) {
@@ -102,30 +151,421 @@ func (ts *taxService) DeleteTax(ctx context.Context, taxId uuid.UUID) (*models.T
 		return nil, taxes.ErrInvalidTaxId
 	}
 
-	return ts.taxRepo.DeleteTax(ctx, taxId)
+	var deleted *models.Tax
+
+	// tax and its history version committed together
+	err := ts.transactor.RunInTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		deleted, err = ts.taxRepo.DeleteTax(ctx, taxId)
+		if err != nil {
+			log.WithFields(log.Fields{"taxId": taxId}).WithError(err).Error("failed to delete tax")
+			return err
+		}
+		if deleted == nil {
+			return nil
+		}
+
+		return ts.recordVersion(ctx, models.TaxActionDeleted, deleted, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func (ts *taxService) GetTaxHistory(ctx context.Context, taxId uuid.UUID) ([]*models.TaxVersion, error) {
+	if taxId == uuid.Nil {
+		log.WithError(taxes.ErrInvalidTaxId).Error("missing tax id")
+		return nil, taxes.ErrInvalidTaxId
+	}
+
+	return ts.historyRepo.GetTaxHistory(ctx, taxId)
+}
+
+// ScheduleRateChange ends validity of the tax at given time and creates a successor tax with new rate valid from
+// given time. For fixed taxes given rate is the new amount per unit.
+func (ts *taxService) ScheduleRateChange(ctx context.Context, taxId uuid.UUID, rate decimal.Decimal, from time.Time) (*models.Tax, error) {
+	if taxId == uuid.Nil {
+		log.WithError(taxes.ErrInvalidTaxId).Error("missing tax id")
+		return nil, taxes.ErrInvalidTaxId
+	}
+	if !rate.IsPositive() {
+		log.WithFields(log.Fields{"taxId": taxId, "rate": rate}).WithError(taxes.ErrInvalidTaxRate).Error("invalid tax rate")
+		return nil, taxes.ErrInvalidTaxRate
+	}
+
+	var exist, nt *models.Tax
+
+	// ending the tax and creating its successor committed together with their history, so the rate can't be lost
+	err := ts.transactor.RunInTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		exist, err = ts.taxRepo.GetTaxByID(ctx, taxId)
+		if err != nil {
+			log.WithFields(log.Fields{"taxId": taxId}).WithError(err).Error("failed to get tax")
+			return err
+		}
+		if exist == nil {
+			log.WithFields(log.Fields{"taxId": taxId}).WithError(taxes.ErrInvalidTaxId).Error("failed to find tax with given id")
+			return taxes.ErrInvalidTaxId
+		}
+
+		if !from.After(time.Now()) || !exist.IsValidAt(from) || (exist.ValidFrom != nil && !from.After(*exist.ValidFrom)) {
+			log.WithFields(log.Fields{"tax": exist, "from": from}).WithError(taxes.ErrInvalidTaxValidity).Error("rate change must be in future and in validity period of tax")
+			return taxes.ErrInvalidTaxValidity
+		}
+
+		old := *exist
+
+		successor := *exist
+		successor.Id = uuid.NewV1()
+		if exist.IsFixed() {
+			successor.Amount = rate
+		} else {
+			successor.Rate = rate
+		}
+		successor.ValidFrom = &from
+
+		exist.ValidTo = &from
+
+		_, err = ts.taxRepo.SaveTax(ctx, exist)
+		if err != nil {
+			log.WithFields(log.Fields{"tax": exist}).WithError(err).Error("failed to end tax validity")
+			return err
+		}
+
+		err = ts.recordVersion(ctx, models.TaxActionUpdated, &old, exist)
+		if err != nil {
+			return err
+		}
+
+		nt, err = ts.taxRepo.SaveTax(ctx, &successor)
+		if err != nil {
+			log.WithFields(log.Fields{"tax": successor}).WithError(err).Error("failed to save successor tax")
+			return err
+		}
+
+		return ts.recordVersion(ctx, models.TaxActionCreated, nil, nt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{"tax": exist, "successor": nt}).Info("tax rate change scheduled")
+	return nt, nil
 }
 
-func (ts *taxService) GetSaleItem(ctx context.Context, item *models.InventoryItem) (*models.SaleItem, error) {
+func (ts *taxService) GetSaleItem(ctx context.Context, item *models.InventoryItem, scope models.TaxScope) (*models.SaleItem, error) {
 	if item == nil {
 		log.WithError(taxes.ErrInvalidParameter).Error("missing item")
 		return nil, taxes.ErrInvalidParameter
 	}
 
-	taxes, err := ts.taxRepo.GetTaxesByItemOriginAndCategory(ctx, item.Origin, item.CategoryId)
+	candidates, err := ts.taxRepo.GetTaxesByItemOriginAndCategory(ctx, item.Origin, item.CategoryId, scope.At)
 	if err != nil {
 		log.WithFields(log.Fields{"item": item}).WithError(err).Error("failed to get suitable taxes for item")
 		return nil, err
 	}
 
-	rate := decimal.Zero
+	taxes := make([]*models.Tax, 0, len(candidates))
+	for _, tax := range candidates {
+		if tax.AppliesInZone(scope.ZoneId) && tax.MatchesRule(item) && !scope.IsExempt(tax.Id) {
+			taxes = append(taxes, tax)
+		}
+	}
 
-	for _, tax := range taxes {
-		rate = rate.Add(tax.Rate)
+	base := item.Price
+	if item.TaxInclusive {
+		base = netPrice(item.Price, taxes)
 	}
 
-	rate = rate.Div(hundred)
+	var breakdown []*models.TaxLine
+
+	switch ts.mode {
+	case models.TaxCalculationPerTax:
+		breakdown = calculateInSequence(base, taxes, calculatePerTax)
+	default:
+		breakdown = calculateInSequence(base, taxes, calculateCombined)
+	}
 
-	taxAmount := item.Price.Mul(rate).Mul(twenty).Ceil().Div(twenty).Round(2)
+	taxAmount := decimal.Zero
+
+	for _, line := range breakdown {
+		taxAmount = taxAmount.Add(line.Amount)
+	}
+
+	gross := item.Price
+	if !item.TaxInclusive {
+		gross = item.Price.Add(taxAmount)
+	}
+
+	return &models.SaleItem{InventoryItem: item, Taxes: taxAmount, Gross: gross, Breakdown: breakdown}, nil
+}
 
-	return &models.SaleItem{InventoryItem: item, Taxes: taxAmount, Gross: taxAmount.Add(item.Price)}, nil
+func (ts *taxService) CreateZone(ctx context.Context, zone *models.Zone) (*models.Zone, error) {
+	if zone == nil {
+		log.WithError(taxes.ErrInvalidParameter).Error("missing zone")
+		return nil, taxes.ErrInvalidParameter
+	}
+	if zone.Name == "" {
+		log.WithFields(log.Fields{"zone": zone}).WithError(taxes.ErrInvalidZoneName).Error("missing zone name")
+		return nil, taxes.ErrInvalidZoneName
+	}
+
+	if zone.Id != uuid.Nil {
+		exist, err := ts.zoneRepo.GetZoneByID(ctx, zone.Id)
+		if err != nil {
+			log.WithFields(log.Fields{"zone": zone}).WithError(err).Error("failed to check existing zones with given id")
+			return nil, err
+		}
+		if exist != nil {
+			log.WithFields(log.Fields{"zone": zone}).WithError(taxes.ErrInvalidZoneId).Error("zone already exist with given id")
+			return nil, taxes.ErrInvalidZoneId
+		}
+	} else {
+		zone.Id = uuid.NewV1()
+	}
+
+	nz, err := ts.zoneRepo.SaveZone(ctx, zone)
+	if err != nil {
+		log.WithFields(log.Fields{"zone": zone}).WithError(err).Error("failed to create zone")
+		return nil, err
+	}
+	return nz, nil
+}
+
+func (ts *taxService) UpdateZone(ctx context.Context, zone *models.Zone) (*models.Zone, error) {
+	if zone == nil {
+		return nil, taxes.ErrInvalidParameter
+	}
+	if zone.Id == uuid.Nil {
+		log.WithFields(log.Fields{"zone": zone}).WithError(taxes.ErrInvalidZoneId).Error("missing zone id")
+		return nil, taxes.ErrInvalidZoneId
+	}
+	if zone.Name == "" {
+		log.WithFields(log.Fields{"zone": zone}).WithError(taxes.ErrInvalidZoneName).Error("missing zone name")
+		return nil, taxes.ErrInvalidZoneName
+	}
+
+	exist, err := ts.zoneRepo.GetZoneByID(ctx, zone.Id)
+	if err != nil {
+		return nil, err
+	}
+	if exist == nil {
+		log.WithFields(log.Fields{"zone": zone}).WithError(taxes.ErrInvalidZoneId).Error("failed to find zone with given id")
+		return nil, taxes.ErrInvalidZoneId
+	}
+
+	nz, err := ts.zoneRepo.SaveZone(ctx, zone)
+	if err != nil {
+		log.WithFields(log.Fields{"zone": zone}).WithError(err).Error("failed to update zone")
+		return nil, err
+	}
+	return nz, nil
+}
+
+func (ts *taxService) GetZoneByID(ctx context.Context, zoneId uuid.UUID) (*models.Zone, error) {
+	if zoneId == uuid.Nil {
+		log.WithError(taxes.ErrInvalidZoneId).Error("missing zone id")
+		return nil, taxes.ErrInvalidZoneId
+	}
+
+	return ts.zoneRepo.GetZoneByID(ctx, zoneId)
+}
+
+func (ts *taxService) FetchAllZones(ctx context.Context) ([]*models.Zone, error) {
+	return ts.zoneRepo.FetchAllZones(ctx)
+}
+
+// DeleteZone deletes zone if there is no tax limited to the zone
+func (ts *taxService) DeleteZone(ctx context.Context, zoneId uuid.UUID) (*models.Zone, error) {
+	if zoneId == uuid.Nil {
+		log.WithError(taxes.ErrInvalidZoneId).Error("missing zone id")
+		return nil, taxes.ErrInvalidZoneId
+	}
+
+	var deleted *models.Zone
+
+	// zone checked and deleted in the same transaction, so no tax can refer it in between
+	err := ts.transactor.RunInTransaction(ctx, func(ctx context.Context) error {
+		txs, err := ts.taxRepo.FetchAllTaxes(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{"zoneId": zoneId}).WithError(err).Error("failed to fetch taxes")
+			return err
+		}
+		for _, tax := range txs {
+			if len(tax.Zones) > 0 && tax.AppliesInZone(zoneId) {
+				log.WithFields(log.Fields{"zoneId": zoneId, "tax": tax}).WithError(taxes.ErrZoneInUse).Error("zone is used by tax")
+				return taxes.ErrZoneInUse
+			}
+		}
+
+		deleted, err = ts.zoneRepo.DeleteZone(ctx, zoneId)
+		if err != nil {
+			log.WithFields(log.Fields{"zoneId": zoneId}).WithError(err).Error("failed to delete zone")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// FindConflicts lists pairs of existing taxes charged together. Besides duplicates rejected on create and update, it
+// reports taxes overlapping in origin, categories, zones and validity, like taxes saved before conflict detection.
+func (ts *taxService) FindConflicts(ctx context.Context) ([]*models.TaxConflict, error) {
+	txs, err := ts.taxRepo.FetchAllTaxes(ctx)
+	if err != nil {
+		log.WithError(err).Error("failed to fetch taxes")
+		return nil, err
+	}
+
+	conflicts := make([]*models.TaxConflict, 0)
+
+	for i, tax := range txs {
+		for _, other := range txs[i+1:] {
+			if reason, ok := conflictBetween(tax, other); ok {
+				conflicts = append(conflicts, &models.TaxConflict{Tax: tax, Conflicting: other, Reason: reason})
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// checkConflicts checks given tax isn't a duplicate of any other existing tax, overlapping taxes are allowed
+func (ts *taxService) checkConflicts(ctx context.Context, tax *models.Tax) error {
+	txs, err := ts.taxRepo.FetchAllTaxes(ctx)
+	if err != nil {
+		log.WithFields(log.Fields{"tax": tax}).WithError(err).Error("failed to fetch taxes")
+		return err
+	}
+
+	for _, other := range txs {
+		if reason, ok := conflictBetween(tax, other); ok && rejected(reason) {
+			log.WithFields(log.Fields{"tax": tax, "conflicting": other, "reason": reason}).WithError(taxes.ErrTaxConflict).Error("tax conflicts with existing tax")
+			return taxes.ErrTaxConflict
+		}
+	}
+	return nil
+}
+
+// checkZones checks zones the tax limited to are exist
+func (ts *taxService) checkZones(ctx context.Context, tax *models.Tax) error {
+	for _, zoneId := range tax.Zones {
+		zone, err := ts.zoneRepo.GetZoneByID(ctx, zoneId)
+		if err != nil {
+			log.WithFields(log.Fields{"tax": tax}).WithError(err).Error("failed to get zone of tax")
+			return err
+		}
+		if zone == nil {
+			log.WithFields(log.Fields{"tax": tax, "zoneId": zoneId}).WithError(taxes.ErrInvalidZoneId).Error("failed to find zone of tax")
+			return taxes.ErrInvalidZoneId
+		}
+	}
+	return nil
+}
+
+// validateTax checks fields of the tax are valid for saving
+func validateTax(tax *models.Tax) error {
+	if tax.Name == "" {
+		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxName).Error("missing tax name")
+		return taxes.ErrInvalidTaxName
+	}
+	switch tax.Kind {
+	case models.TaxKindFixed:
+		if !tax.Amount.IsPositive() {
+			log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxAmount).Error("invalid fixed tax amount")
+			return taxes.ErrInvalidTaxAmount
+		}
+	case "", models.TaxKindPercentage:
+		if !tax.Rate.IsPositive() {
+			log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxRate).Error("invalid tax rate")
+			return taxes.ErrInvalidTaxRate
+		}
+	default:
+		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxKind).Error("unknown tax kind")
+		return taxes.ErrInvalidTaxKind
+	}
+	if !tax.Origin.IsValid() {
+		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxOrigin).Error("invalid tax origin")
+		return taxes.ErrInvalidTaxOrigin
+	}
+	if err := checkCondition(tax); err != nil {
+		return err
+	}
+	if tax.Rounding != nil && !tax.Rounding.IsValid() {
+		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxRounding).Error("invalid tax rounding rule")
+		return taxes.ErrInvalidTaxRounding
+	}
+	if tax.ValidFrom != nil && tax.ValidTo != nil && !tax.ValidTo.After(*tax.ValidFrom) {
+		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxValidity).Error("tax validity ends before it starts")
+		return taxes.ErrInvalidTaxValidity
+	}
+	if tax.Rule != nil && !tax.Rule.IsValid() {
+		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxRule).Error("invalid tax rule")
+		return taxes.ErrInvalidTaxRule
+	}
+	return nil
+}
+
+// checkCondition checks category condition of the tax is consistent with its categories, missing condition
+// means all categories when tax has no categories
+func checkCondition(tax *models.Tax) error {
+	if tax.Condition == "" && len(tax.Categories) == 0 {
+		tax.Condition = models.AllCategories
+	}
+
+	switch {
+	case !tax.Condition.IsValid():
+		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxCondition).Error("invalid tax condition")
+		return taxes.ErrInvalidTaxCondition
+	case tax.Condition == models.AllCategories && len(tax.Categories) > 0:
+		log.WithFields(log.Fields{"tax": tax}).WithError(taxes.ErrInvalidTaxCondition).Error("tax applies to all categories but lists categories")
+		return taxes.ErrInvalidTaxCondition
+	}
+	return nil
+}
+
+// checkCategories checks categories referred by the tax are exist
+func (ts *taxService) checkCategories(ctx context.Context, tax *models.Tax) error {
+	for categoryId := range tax.Categories {
+		cat, err := ts.categoryRepo.GetCategoryByID(ctx, categoryId)
+		if err != nil {
+			log.WithFields(log.Fields{"tax": tax}).WithError(err).Error("failed to get category of tax")
+			return err
+		}
+		if cat == nil {
+			log.WithFields(log.Fields{"tax": tax, "categoryId": categoryId}).WithError(taxes.ErrInvalidTaxCategory).Error("failed to find category of tax")
+			return taxes.ErrInvalidTaxCategory
+		}
+	}
+	return nil
+}
+
+// recordVersion keeps the change made on a tax in tax history with the actor in context
+func (ts *taxService) recordVersion(ctx context.Context, action models.TaxAction, old *models.Tax, new *models.Tax) error {
+	version := &models.TaxVersion{
+		Action:    action,
+		ChangedBy: audit.ActorFromContext(ctx),
+		ChangedAt: time.Now(),
+		Old:       old,
+		New:       new,
+	}
+
+	if new != nil {
+		version.TaxId = new.Id
+	} else {
+		version.TaxId = old.Id
+	}
+
+	_, err := ts.historyRepo.SaveTaxVersion(ctx, version)
+	if err != nil {
+		log.WithFields(log.Fields{"version": version}).WithError(err).Error("failed to record tax version")
+		return err
+	}
+	return nil
 }
@@ -2,6 +2,10 @@ package service_test
 
 import (
 	"context"
+	"errors"
+	"github.com/aweris/stp/internal/audit"
+	"github.com/aweris/stp/internal/inventory"
+	inventoryRepository "github.com/aweris/stp/internal/inventory/repository"
 	"github.com/aweris/stp/internal/models"
 	"github.com/aweris/stp/internal/taxes"
 	taxRepository "github.com/aweris/stp/internal/taxes/repository"
@@ -10,29 +14,47 @@ import (
 	"github.com/satori/go.uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
+	"time"
 )
 
 type mockedService struct {
 	taxes.TaxService
 
 	db *storage.TestDB
+
+	cr inventory.CategoryRepository
 }
 
 func newMockedService() *mockedService {
+	return newMockedServiceWithMode(models.TaxCalculationCombined)
+}
+
+func newMockedServiceWithMode(mode models.TaxCalculationMode) *mockedService {
 	db := storage.NewTestDB()
 
 	tr := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+	thr := taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+	zr := taxRepository.NewBoltDBZoneRepository(db.BoltDB)
+	cr := inventoryRepository.NewBoltDBCategoryRepository(db.BoltDB)
 
-	ts := taxService.NewTaxService(tr)
+	ts := taxService.NewTaxService(tr, thr, zr, cr, db.BoltDB, mode)
 
-	return &mockedService{db: db, TaxService: ts}
+	return &mockedService{db: db, TaxService: ts, cr: cr}
 }
 
 func (ms *mockedService) Close() {
 	ms.db.Close()
 }
 
+// newCategoryId saves a new category for taxes to refer and returns its id
+func (ms *mockedService) newCategoryId(t *testing.T) uuid.UUID {
+	c, err := ms.cr.SaveCategory(context.Background(), &models.Category{Id: uuid.NewV1(), Name: uuid.NewV1().String()})
+	assert.NoError(t, err, "failed to add category")
+	return c.Id
+}
+
 func TestTaxService_CreateTax_WithNilParameter_ThanShouldReturnErr(t *testing.T) {
 	ts := newMockedService()
 	defer ts.Close()
@@ -50,7 +72,7 @@ func TestTaxService_CreateTax_WithEmptyName_ThanShouldReturnErr(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	_, err := ts.TaxService.CreateTax(context.Background(), tax)
@@ -67,7 +89,7 @@ func TestTaxService_CreateTax_WhenRateIsNegative_ThanShouldReturnErr(t *testing.
 		Rate:       decimal.NewFromFloat32(-10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	_, err := ts.TaxService.CreateTax(context.Background(), tax)
@@ -83,7 +105,7 @@ func TestTaxService_CreateTax_WhenRateIsMissing_ThenShouldReturnErr(t *testing.T
 		Name:       "Test Rate",
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	_, err := ts.TaxService.CreateTax(context.Background(), tax)
@@ -99,7 +121,7 @@ func TestTaxService_CreateTax_WhenIdIsMissing_ThanShouldGenerateAndCreateTax(t *
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
@@ -118,7 +140,7 @@ func TestTaxService_CreateTax_WhenNonExistingId_ThanShouldCreateTax(t *testing.T
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
@@ -137,7 +159,7 @@ func TestTaxService_CreateTax_WhenExistingId_ThanShouldReturnErr(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	_, err := ts.TaxService.CreateTax(context.Background(), existing)
@@ -149,7 +171,7 @@ func TestTaxService_CreateTax_WhenExistingId_ThanShouldReturnErr(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	_, err = ts.TaxService.CreateTax(context.Background(), tax)
@@ -173,7 +195,7 @@ func TestTaxService_UpdateTax_WithNilId_ThanShouldReturnErr(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	_, err := ts.TaxService.UpdateTax(context.Background(), tax)
@@ -190,7 +212,7 @@ func TestTaxService_UpdateTax_WithEmptyName_ThanShouldReturnErr(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
 	assert.NoError(t, err)
@@ -211,7 +233,7 @@ func TestTaxService_UpdateTax_WhenRateIsNegative_ThanShouldReturnErr(t *testing.
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
 	assert.NoError(t, err)
@@ -232,7 +254,7 @@ func TestTaxService_UpdateTax_WhenIdNonExist_ThenShouldReturnErr(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 	_, err := ts.TaxService.UpdateTax(context.Background(), tax)
 	assert.Equal(t, err, taxes.ErrInvalidTaxId)
@@ -248,7 +270,7 @@ func TestTaxService_UpdateTax_ShouldUpdate(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
 	assert.NoError(t, err)
@@ -280,7 +302,7 @@ func TestTaxService_GetTaxByID_ShouldReturnTax(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
 	assert.NoError(t, err)
@@ -300,7 +322,7 @@ func TestTaxService_FetchAllTaxes_ShouldReturnList(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
 	assert.NoError(t, err)
@@ -329,7 +351,7 @@ func TestTaxService_DeleteTax_ShouldDeleteTax(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
 	assert.NoError(t, err)
@@ -339,6 +361,51 @@ func TestTaxService_DeleteTax_ShouldDeleteTax(t *testing.T) {
 	assert.NotNil(t, deleted)
 }
 
+// failingHistoryRepository fails to record any tax version
+type failingHistoryRepository struct {
+	taxes.TaxHistoryRepository
+}
+
+func (failingHistoryRepository) SaveTaxVersion(ctx context.Context, version *models.TaxVersion) (*models.TaxVersion, error) {
+	return nil, errors.New("history is not available")
+}
+
+func TestTaxService_UpdateAndDeleteTax_WhenHistoryNotRecorded_ThenShouldKeepTax(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Id:     uuid.NewV1(),
+		Name:   "Sale Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	tr := taxRepository.NewBoltDBTaxRepository(ts.db.BoltDB)
+	zr := taxRepository.NewBoltDBZoneRepository(ts.db.BoltDB)
+	fs := taxService.NewTaxService(tr, failingHistoryRepository{}, zr, ts.cr, ts.db.BoltDB, models.TaxCalculationCombined)
+
+	update := *tax
+	update.Rate = decimal.NewFromFloat32(20)
+
+	_, err = fs.UpdateTax(context.Background(), &update)
+	assert.Error(t, err)
+
+	_, err = fs.DeleteTax(context.Background(), tax.Id)
+	assert.Error(t, err)
+
+	find, err := ts.TaxService.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err)
+	assert.NotNil(t, find)
+	assert.True(t, decimal.NewFromFloat32(10).Equal(find.Rate))
+
+	history, err := ts.TaxService.GetTaxHistory(context.Background(), tax.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(history))
+}
+
 func TestTaxService_GetSaleItem_ShouldReturnSaleItem(t *testing.T) {
 	ts := newMockedService()
 	defer ts.Close()
@@ -349,7 +416,7 @@ func TestTaxService_GetSaleItem_ShouldReturnSaleItem(t *testing.T) {
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
@@ -362,7 +429,7 @@ func TestTaxService_GetSaleItem_ShouldReturnSaleItem(t *testing.T) {
 		Price:      decimal.NewFromFloat32(14.99),
 	}
 
-	si, err := ts.TaxService.GetSaleItem(context.Background(), i)
+	si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
 	assert.NoError(t, err)
 	assert.True(t, si.Gross.Equal(decimal.NewFromFloat32(16.49)))
 }
@@ -377,7 +444,7 @@ func TestTaxService_GetSaleItem_WhenHaveMultipleTax_ThenShouldReturnSaleItem(t *
 		Rate:       decimal.NewFromFloat32(10),
 		Origin:     models.TaxOriginAll,
 		Condition:  models.ExemptToTax,
-		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
 	}
 
 	_, err := ts.TaxService.CreateTax(context.Background(), bst)
@@ -400,7 +467,7 @@ func TestTaxService_GetSaleItem_WhenHaveMultipleTax_ThenShouldReturnSaleItem(t *
 		Price:      decimal.NewFromFloat32(47.50),
 	}
 
-	si, err := ts.TaxService.GetSaleItem(context.Background(), i)
+	si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
 	assert.NoError(t, err)
 	assert.True(t, si.Gross.Equal(decimal.NewFromFloat32(54.65)))
 }
@@ -409,7 +476,7 @@ func TestTaxService_GetSaleItem_WhenHaveMultipleTaxesButOnlyOneOkay_ThanShouldAp
 	ts := newMockedService()
 	defer ts.Close()
 
-	exemptId := uuid.NewV1()
+	exemptId := ts.newCategoryId(t)
 
 	bst := &models.Tax{
 		Id:         uuid.NewV1(),
@@ -440,7 +507,1047 @@ func TestTaxService_GetSaleItem_WhenHaveMultipleTaxesButOnlyOneOkay_ThanShouldAp
 		Price:      decimal.NewFromFloat32(10),
 	}
 
-	si, err := ts.TaxService.GetSaleItem(context.Background(), i)
+	si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
 	assert.NoError(t, err)
 	assert.True(t, si.Gross.Equal(decimal.NewFromFloat32(10.5)))
 }
+
+func TestTaxService_CreateTax_WhenRoundingRuleIsInvalid_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:     "Test Rate",
+		Rate:     decimal.NewFromFloat32(10),
+		Origin:   models.TaxOriginAll,
+		Rounding: &models.RoundingRule{Increment: decimal.Zero, Mode: models.RoundingModeCeil},
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.Equal(t, err, taxes.ErrInvalidTaxRounding)
+}
+
+func TestTaxService_UpdateTax_WhenRoundingModeIsUnknown_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Will be Updated",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	tax.Rounding = &models.RoundingRule{Increment: decimal.NewFromFloat32(0.01), Mode: "NOT OPTION"}
+
+	_, err = ts.TaxService.UpdateTax(context.Background(), tax)
+	assert.Equal(t, err, taxes.ErrInvalidTaxRounding)
+}
+
+func TestTaxService_GetSaleItem_WhenTaxHasRoundingRule_ThenShouldRoundWithRule(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:     "Half Up Tax",
+		Rate:     decimal.NewFromFloat32(10),
+		Origin:   models.TaxOriginAll,
+		Rounding: &models.RoundingRule{Increment: decimal.NewFromFloat32(0.01), Mode: models.RoundingModeHalfUp},
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: uuid.NewV1(),
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(14.99),
+	}
+
+	si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(1.5)))
+
+	i.Price = decimal.NewFromFloat32(14.94)
+
+	si, err = ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(1.49)))
+}
+
+func TestTaxService_GetSaleItem_WhenTaxesHaveDifferentRoundingRules_ThenShouldRoundEachGroup(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	bst := &models.Tax{
+		Name:   "Sale Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), bst)
+	assert.NoError(t, err)
+
+	it := &models.Tax{
+		Name:     "Import Tax",
+		Rate:     decimal.NewFromFloat32(5),
+		Origin:   models.TaxOriginImport,
+		Rounding: &models.RoundingRule{Increment: decimal.NewFromFloat32(0.01), Mode: models.RoundingModeFloor},
+	}
+
+	_, err = ts.TaxService.CreateTax(context.Background(), it)
+	assert.NoError(t, err)
+
+	i := &models.InventoryItem{
+		Name:       "Perfume",
+		CategoryId: uuid.NewV1(),
+		Origin:     models.ItemOriginImported,
+		Price:      decimal.NewFromFloat32(27.99),
+	}
+
+	si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(4.19)))
+}
+
+func TestTaxService_GetSaleItem_WhenModeIsPerTax_ThenShouldRoundEachTaxIndividually(t *testing.T) {
+	ctx := context.Background()
+
+	bst := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	it := &models.Tax{
+		Name:   "Import Duty",
+		Rate:   decimal.NewFromFloat32(5),
+		Origin: models.TaxOriginImport,
+	}
+
+	i := &models.InventoryItem{
+		Name:       "Perfume",
+		CategoryId: uuid.NewV1(),
+		Origin:     models.ItemOriginImported,
+		Price:      decimal.NewFromFloat32(1.10),
+	}
+
+	combined := newMockedServiceWithMode(models.TaxCalculationCombined)
+	defer combined.Close()
+
+	perTax := newMockedServiceWithMode(models.TaxCalculationPerTax)
+	defer perTax.Close()
+
+	for _, ts := range []*mockedService{combined, perTax} {
+		_, err := ts.TaxService.CreateTax(ctx, &models.Tax{Name: bst.Name, Rate: bst.Rate, Origin: bst.Origin})
+		assert.NoError(t, err)
+
+		_, err = ts.TaxService.CreateTax(ctx, &models.Tax{Name: it.Name, Rate: it.Rate, Origin: it.Origin})
+		assert.NoError(t, err)
+	}
+
+	si, err := combined.TaxService.GetSaleItem(ctx, i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(0.2)))
+
+	si, err = perTax.TaxService.GetSaleItem(ctx, i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(0.25)))
+	assert.Equal(t, 2, len(si.Breakdown))
+
+	amounts := make(map[string]decimal.Decimal)
+	for _, line := range si.Breakdown {
+		amounts[line.Name] = line.Amount
+	}
+
+	assert.True(t, amounts[bst.Name].Equal(decimal.NewFromFloat32(0.15)))
+	assert.True(t, amounts[it.Name].Equal(decimal.NewFromFloat32(0.1)))
+}
+
+func TestTaxService_GetSaleItem_WhenModeIsCombined_ThenBreakdownShouldSumToTaxes(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	bst := &models.Tax{
+		Name:   "Basic Sales Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), bst)
+	assert.NoError(t, err)
+
+	it := &models.Tax{
+		Name:   "Import Duty",
+		Rate:   decimal.NewFromFloat32(5),
+		Origin: models.TaxOriginImport,
+	}
+
+	_, err = ts.TaxService.CreateTax(context.Background(), it)
+	assert.NoError(t, err)
+
+	i := &models.InventoryItem{
+		Name:       "Perfume",
+		CategoryId: uuid.NewV1(),
+		Origin:     models.ItemOriginImported,
+		Price:      decimal.NewFromFloat32(1.10),
+	}
+
+	si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(si.Breakdown))
+
+	sum := decimal.Zero
+	for _, line := range si.Breakdown {
+		sum = sum.Add(line.Amount)
+	}
+
+	assert.True(t, sum.Equal(si.Taxes))
+}
+
+func TestTaxService_GetSaleItem_WhenPriceIsTaxInclusive_ThenShouldBackCalculateTaxes(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Sale Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	i := &models.InventoryItem{
+		Name:         "Test Item",
+		CategoryId:   uuid.NewV1(),
+		Origin:       models.ItemOriginLocal,
+		Price:        decimal.NewFromFloat32(16.49),
+		TaxInclusive: true,
+	}
+
+	si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, si.Gross.Equal(decimal.NewFromFloat32(16.49)))
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(1.5)))
+	assert.True(t, si.Net().Equal(decimal.NewFromFloat32(14.99)))
+}
+
+func TestTaxService_CreateTax_WhenValidToIsBeforeValidFrom_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	from := time.Now()
+	to := from.Add(-time.Hour)
+
+	tax := &models.Tax{
+		Name:      "Test Rate",
+		Rate:      decimal.NewFromFloat32(10),
+		Origin:    models.TaxOriginAll,
+		ValidFrom: &from,
+		ValidTo:   &to,
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.Equal(t, err, taxes.ErrInvalidTaxValidity)
+}
+
+func TestTaxService_ScheduleRateChange_ShouldApplyNewRateFromGivenTime(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Sale Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	from := time.Now().Add(time.Hour)
+
+	successor, err := ts.TaxService.ScheduleRateChange(context.Background(), tax.Id, decimal.NewFromFloat32(20), from)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tax.Id, successor.Id)
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: uuid.NewV1(),
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(10),
+	}
+
+	si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(1)))
+
+	si, err = ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: from})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(2)))
+}
+
+func TestTaxService_ScheduleRateChange_WhenTimeIsInPast_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Sale Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	_, err = ts.TaxService.ScheduleRateChange(context.Background(), tax.Id, decimal.NewFromFloat32(20), time.Now().Add(-time.Hour))
+	assert.Equal(t, err, taxes.ErrInvalidTaxValidity)
+}
+
+func TestTaxService_GetTaxHistory_ShouldReturnEveryChangeWithActor(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := audit.WithActor(context.Background(), "auditor")
+
+	tax := &models.Tax{
+		Name:   "Sale Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	tax, err := ts.TaxService.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	updated := &models.Tax{
+		Id:     tax.Id,
+		Name:   "Sale Tax",
+		Rate:   decimal.NewFromFloat32(20),
+		Origin: models.TaxOriginAll,
+	}
+
+	_, err = ts.TaxService.UpdateTax(ctx, updated)
+	assert.NoError(t, err)
+
+	_, err = ts.TaxService.DeleteTax(context.Background(), tax.Id)
+	assert.NoError(t, err)
+
+	history, err := ts.TaxService.GetTaxHistory(ctx, tax.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(history))
+
+	assert.Equal(t, models.TaxActionCreated, history[0].Action)
+	assert.Nil(t, history[0].Old)
+	assert.Equal(t, "auditor", history[0].ChangedBy)
+
+	assert.Equal(t, models.TaxActionUpdated, history[1].Action)
+	assert.True(t, history[1].Old.Rate.Equal(decimal.NewFromFloat32(10)))
+	assert.True(t, history[1].New.Rate.Equal(decimal.NewFromFloat32(20)))
+
+	assert.Equal(t, models.TaxActionDeleted, history[2].Action)
+	assert.Nil(t, history[2].New)
+	assert.Equal(t, audit.SystemActor, history[2].ChangedBy)
+}
+
+func TestTaxService_GetTaxHistory_WhenIdIsNil_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	_, err := ts.TaxService.GetTaxHistory(context.Background(), uuid.Nil)
+	assert.Equal(t, err, taxes.ErrInvalidTaxId)
+}
+
+func TestTaxService_CreateTax_WhenRuleIsInvalid_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Luxury Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+		Rule:   &models.TaxRule{Type: models.TaxRuleAnd, Rules: []*models.TaxRule{{Type: models.TaxRulePriceAbove}}},
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.Equal(t, err, taxes.ErrInvalidTaxRule)
+}
+
+func TestTaxService_GetSaleItem_WhenTaxHasRule_ThenShouldApplyOnlyToMatchingItems(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	threshold := decimal.NewFromFloat32(1000)
+	exempt := uuid.NewV1()
+
+	// luxury tax for items above 1000 or tagged as luxury, except the exempt item
+	tax := &models.Tax{
+		Name:   "Luxury Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+		Rule: &models.TaxRule{
+			Type: models.TaxRuleAnd,
+			Rules: []*models.TaxRule{
+				{
+					Type: models.TaxRuleOr,
+					Rules: []*models.TaxRule{
+						{Type: models.TaxRulePriceAbove, Amount: &threshold},
+						{Type: models.TaxRuleTags, Tags: []string{"luxury"}},
+					},
+				},
+				{
+					Type:  models.TaxRuleNot,
+					Rules: []*models.TaxRule{{Type: models.TaxRuleItems, Items: []uuid.UUID{exempt}}},
+				},
+			},
+		},
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	cases := []struct {
+		item  *models.InventoryItem
+		taxes decimal.Decimal
+	}{
+		{&models.InventoryItem{Id: uuid.NewV1(), Price: decimal.NewFromFloat32(1500)}, decimal.NewFromFloat32(150)},
+		{&models.InventoryItem{Id: uuid.NewV1(), Price: decimal.NewFromFloat32(500)}, decimal.Zero},
+		{&models.InventoryItem{Id: uuid.NewV1(), Price: decimal.NewFromFloat32(500), Tags: []string{"Luxury"}}, decimal.NewFromFloat32(50)},
+		{&models.InventoryItem{Id: exempt, Price: decimal.NewFromFloat32(1500)}, decimal.Zero},
+	}
+
+	for _, c := range cases {
+		c.item.Origin = models.ItemOriginLocal
+		c.item.CategoryId = uuid.NewV1()
+
+		si, err := ts.TaxService.GetSaleItem(context.Background(), c.item, models.TaxScope{At: time.Now()})
+		assert.NoError(t, err)
+		assert.True(t, si.Taxes.Equal(c.taxes), "unexpected taxes %s for item %s", si.Taxes, c.item)
+	}
+}
+
+func TestTaxService_GetSaleItem_WhenTaxIsCompound_ThenShouldLevyOnPriceWithPreviousTaxes(t *testing.T) {
+	for _, mode := range []models.TaxCalculationMode{models.TaxCalculationCombined, models.TaxCalculationPerTax} {
+		ts := newMockedServiceWithMode(mode)
+
+		rounding := &models.RoundingRule{Increment: decimal.New(1, -2), Mode: models.RoundingModeHalfUp}
+
+		// created in reverse order, ordering must come from priority
+		provincial := &models.Tax{
+			Name:     "Provincial Tax",
+			Rate:     decimal.NewFromFloat32(10),
+			Origin:   models.TaxOriginAll,
+			Rounding: rounding,
+			Priority: 1,
+			Compound: true,
+		}
+		federal := &models.Tax{
+			Name:     "Federal Tax",
+			Rate:     decimal.NewFromFloat32(5),
+			Origin:   models.TaxOriginAll,
+			Rounding: rounding,
+		}
+
+		_, err := ts.TaxService.CreateTax(context.Background(), provincial)
+		assert.NoError(t, err)
+		_, err = ts.TaxService.CreateTax(context.Background(), federal)
+		assert.NoError(t, err)
+
+		i := &models.InventoryItem{
+			Name:       "Test Item",
+			CategoryId: uuid.NewV1(),
+			Origin:     models.ItemOriginLocal,
+			Price:      decimal.NewFromFloat32(100),
+		}
+
+		si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+		assert.NoError(t, err)
+		assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(15.5)), "unexpected taxes %s in mode %s", si.Taxes, mode)
+
+		assert.Equal(t, 2, len(si.Breakdown))
+		assert.Equal(t, "Federal Tax", si.Breakdown[0].Name)
+		assert.True(t, si.Breakdown[0].Base.Equal(decimal.NewFromFloat32(100)))
+		assert.True(t, si.Breakdown[0].Amount.Equal(decimal.NewFromFloat32(5)))
+		assert.Equal(t, "Provincial Tax", si.Breakdown[1].Name)
+		assert.True(t, si.Breakdown[1].Base.Equal(decimal.NewFromFloat32(105)))
+		assert.True(t, si.Breakdown[1].Amount.Equal(decimal.NewFromFloat32(10.5)))
+
+		i.Price = decimal.NewFromFloat32(115.5)
+		i.TaxInclusive = true
+
+		si, err = ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+		assert.NoError(t, err)
+		assert.True(t, si.Net().Equal(decimal.NewFromFloat32(100)), "unexpected net %s in mode %s", si.Net(), mode)
+
+		ts.Close()
+	}
+}
+
+func TestTaxService_CreateTax_WhenFixedTaxAmountIsMissing_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Eco Fee",
+		Kind:   models.TaxKindFixed,
+		Origin: models.TaxOriginAll,
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.Equal(t, err, taxes.ErrInvalidTaxAmount)
+}
+
+func TestTaxService_CreateTax_WhenKindIsUnknown_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Test Tax",
+		Kind:   "NOT OPTION",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.Equal(t, err, taxes.ErrInvalidTaxKind)
+}
+
+func TestTaxService_GetSaleItem_WhenTaxIsFixed_ThenShouldAddAmountWithoutRounding(t *testing.T) {
+	for _, mode := range []models.TaxCalculationMode{models.TaxCalculationCombined, models.TaxCalculationPerTax} {
+		ts := newMockedServiceWithMode(mode)
+
+		fee := &models.Tax{
+			Name:   "Eco Fee",
+			Kind:   models.TaxKindFixed,
+			Amount: decimal.NewFromFloat32(0.12),
+			Origin: models.TaxOriginAll,
+		}
+		// sales tax levied on price plus eco fee
+		vat := &models.Tax{
+			Name:     "Sale Tax",
+			Rate:     decimal.NewFromFloat32(10),
+			Origin:   models.TaxOriginAll,
+			Rounding: &models.RoundingRule{Increment: decimal.New(1, -2), Mode: models.RoundingModeHalfUp},
+			Priority: 1,
+			Compound: true,
+		}
+
+		_, err := ts.TaxService.CreateTax(context.Background(), fee)
+		assert.NoError(t, err)
+		_, err = ts.TaxService.CreateTax(context.Background(), vat)
+		assert.NoError(t, err)
+
+		i := &models.InventoryItem{
+			Name:       "Test Item",
+			CategoryId: uuid.NewV1(),
+			Origin:     models.ItemOriginLocal,
+			Price:      decimal.NewFromFloat32(10),
+		}
+
+		si, err := ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+		assert.NoError(t, err)
+		assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(1.13)), "unexpected taxes %s in mode %s", si.Taxes, mode)
+		assert.True(t, si.Gross.Equal(decimal.NewFromFloat32(11.13)))
+
+		i.Price = decimal.NewFromFloat32(11.13)
+		i.TaxInclusive = true
+
+		si, err = ts.TaxService.GetSaleItem(context.Background(), i, models.TaxScope{At: time.Now()})
+		assert.NoError(t, err)
+		assert.True(t, si.Net().Equal(decimal.NewFromFloat32(10)), "unexpected net %s in mode %s", si.Net(), mode)
+
+		ts.Close()
+	}
+}
+
+func TestTaxService_CreateTax_WhenZoneNotExist_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Provincial Tax",
+		Rate:   decimal.NewFromFloat32(8),
+		Origin: models.TaxOriginAll,
+		Zones:  []uuid.UUID{uuid.NewV1()},
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.Equal(t, err, taxes.ErrInvalidZoneId)
+}
+
+func TestTaxService_GetSaleItem_WhenTaxHasZones_ThenShouldApplyOnlyInZones(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	ontario, err := ts.TaxService.CreateZone(ctx, &models.Zone{Name: "Ontario"})
+	assert.NoError(t, err)
+	quebec, err := ts.TaxService.CreateZone(ctx, &models.Zone{Name: "Quebec"})
+	assert.NoError(t, err)
+
+	federal := &models.Tax{
+		Name:   "Federal Tax",
+		Rate:   decimal.NewFromFloat32(5),
+		Origin: models.TaxOriginAll,
+	}
+	provincial := &models.Tax{
+		Name:   "Provincial Tax",
+		Rate:   decimal.NewFromFloat32(8),
+		Origin: models.TaxOriginAll,
+		Zones:  []uuid.UUID{ontario.Id},
+	}
+
+	_, err = ts.TaxService.CreateTax(ctx, federal)
+	assert.NoError(t, err)
+	_, err = ts.TaxService.CreateTax(ctx, provincial)
+	assert.NoError(t, err)
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: uuid.NewV1(),
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(100),
+	}
+
+	si, err := ts.TaxService.GetSaleItem(ctx, i, models.TaxScope{At: time.Now(), ZoneId: ontario.Id})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(13)))
+
+	si, err = ts.TaxService.GetSaleItem(ctx, i, models.TaxScope{At: time.Now(), ZoneId: quebec.Id})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(5)))
+
+	si, err = ts.TaxService.GetSaleItem(ctx, i, models.TaxScope{At: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(5)))
+}
+
+func TestTaxService_DeleteZone_WhenTaxUsesZone_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	zone, err := ts.TaxService.CreateZone(ctx, &models.Zone{Name: "Ontario"})
+	assert.NoError(t, err)
+
+	tax := &models.Tax{
+		Name:   "Provincial Tax",
+		Rate:   decimal.NewFromFloat32(8),
+		Origin: models.TaxOriginAll,
+		Zones:  []uuid.UUID{zone.Id},
+	}
+	tax, err = ts.TaxService.CreateTax(ctx, tax)
+	assert.NoError(t, err)
+
+	_, err = ts.TaxService.DeleteZone(ctx, zone.Id)
+	assert.Equal(t, err, taxes.ErrZoneInUse)
+
+	_, err = ts.TaxService.DeleteTax(ctx, tax.Id)
+	assert.NoError(t, err)
+
+	deleted, err := ts.TaxService.DeleteZone(ctx, zone.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, zone.Id, deleted.Id)
+}
+
+func TestTaxService_DeleteZone_WhenTaxCreatedConcurrently_ThenOnlyOneShouldSucceed(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	for n := 0; n < 10; n++ {
+		zone, err := ts.TaxService.CreateZone(context.Background(), &models.Zone{Name: uuid.NewV1().String()})
+		assert.NoError(t, err)
+
+		tax := &models.Tax{
+			Name:   uuid.NewV1().String(),
+			Rate:   decimal.NewFromFloat32(8),
+			Origin: models.TaxOriginAll,
+			Zones:  []uuid.UUID{zone.Id},
+		}
+
+		var wg sync.WaitGroup
+		var taxErr, deleteErr error
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, taxErr = ts.TaxService.CreateTax(context.Background(), tax)
+		}()
+		go func() {
+			defer wg.Done()
+			_, deleteErr = ts.TaxService.DeleteZone(context.Background(), zone.Id)
+		}()
+		wg.Wait()
+
+		if taxErr == nil {
+			assert.Equal(t, taxes.ErrZoneInUse, deleteErr)
+		} else {
+			assert.Equal(t, taxes.ErrInvalidZoneId, taxErr)
+			assert.NoError(t, deleteErr)
+		}
+	}
+}
+
+func TestTaxService_CreateTax_WhenSameTaxExist_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	books := ts.newCategoryId(t)
+	food := ts.newCategoryId(t)
+
+	tax := &models.Tax{
+		Name:       "Basic Sale Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.ExemptToTax,
+		Categories: map[uuid.UUID]bool{books: true, food: true},
+	}
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	duplicate := &models.Tax{
+		Name:       "basic sale tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginLocal,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
+	}
+	_, err = ts.TaxService.CreateTax(context.Background(), duplicate)
+	assert.Equal(t, taxes.ErrTaxConflict, err)
+
+	renamed := &models.Tax{
+		Name:       "Sales Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.ExemptToTax,
+		Categories: map[uuid.UUID]bool{food: true, books: true},
+	}
+	_, err = ts.TaxService.CreateTax(context.Background(), renamed)
+	assert.Equal(t, taxes.ErrTaxConflict, err)
+}
+
+func TestTaxService_CreateTax_WhenSameNamedTaxDoesNotOverlap_ThenShouldCreateTax(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	books := ts.newCategoryId(t)
+	until := time.Now().Add(time.Hour)
+
+	tax := &models.Tax{
+		Name:       "Basic Sale Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{books: true},
+		ValidTo:    &until,
+	}
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	// exempts only category of other tax
+	exempt := &models.Tax{
+		Name:       "Basic Sale Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.ExemptToTax,
+		Categories: map[uuid.UUID]bool{books: true},
+	}
+	_, err = ts.TaxService.CreateTax(context.Background(), exempt)
+	assert.NoError(t, err)
+
+	// starts when other tax ends
+	successor := &models.Tax{
+		Name:       "Basic Sale Tax",
+		Rate:       decimal.NewFromFloat32(12),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{books: true},
+		ValidFrom:  &until,
+	}
+	_, err = ts.TaxService.CreateTax(context.Background(), successor)
+	assert.NoError(t, err)
+
+	importDuty := &models.Tax{
+		Name:   "Import Duty",
+		Rate:   decimal.NewFromFloat32(5),
+		Origin: models.TaxOriginImport,
+	}
+	importDuty, err = ts.TaxService.CreateTax(context.Background(), importDuty)
+	assert.NoError(t, err)
+
+	// import duty only overlaps with basic sale taxes
+	conflicts, err := ts.TaxService.FindConflicts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(conflicts))
+	for _, c := range conflicts {
+		assert.Equal(t, models.TaxConflictOverlap, c.Reason)
+		assert.True(t, uuid.Equal(importDuty.Id, c.Tax.Id) || uuid.Equal(importDuty.Id, c.Conflicting.Id))
+	}
+}
+
+func TestTaxService_FindConflicts_ShouldReportOverlappingTaxes(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	books := ts.newCategoryId(t)
+	food := ts.newCategoryId(t)
+	until := time.Now().Add(time.Hour)
+
+	// overlaps with local taxes on books until it ends
+	importDuty := &models.Tax{
+		Name:       "Import Duty",
+		Rate:       decimal.NewFromFloat32(5),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{books: true, food: true},
+		ValidTo:    &until,
+	}
+	importDuty, err := ts.TaxService.CreateTax(ctx, importDuty)
+	assert.NoError(t, err)
+
+	bookTax := &models.Tax{
+		Name:       "Book Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginLocal,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{books: true},
+	}
+	bookTax, err = ts.TaxService.CreateTax(ctx, bookTax)
+	assert.NoError(t, err)
+
+	// applies only to imported items
+	importedFood := &models.Tax{
+		Name:       "Imported Food Tax",
+		Rate:       decimal.NewFromFloat32(3),
+		Origin:     models.TaxOriginImport,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{food: true},
+	}
+	_, err = ts.TaxService.CreateTax(ctx, importedFood)
+	assert.NoError(t, err)
+
+	// starts when import duty ends
+	successor := &models.Tax{
+		Name:       "Local Food Tax",
+		Rate:       decimal.NewFromFloat32(2),
+		Origin:     models.TaxOriginLocal,
+		Condition:  models.SubjectToTax,
+		Categories: map[uuid.UUID]bool{food: true},
+		ValidFrom:  &until,
+	}
+	_, err = ts.TaxService.CreateTax(ctx, successor)
+	assert.NoError(t, err)
+
+	conflicts, err := ts.TaxService.FindConflicts(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(conflicts))
+
+	for _, c := range conflicts {
+		assert.Equal(t, models.TaxConflictOverlap, c.Reason)
+		assert.True(t, uuid.Equal(importDuty.Id, c.Tax.Id) || uuid.Equal(importDuty.Id, c.Conflicting.Id))
+		assert.False(t, uuid.Equal(successor.Id, c.Tax.Id) || uuid.Equal(successor.Id, c.Conflicting.Id))
+	}
+}
+
+func TestTaxService_FindConflicts_ShouldReturnExistingConflicts(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	tr := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+	thr := taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+	zr := taxRepository.NewBoltDBZoneRepository(db.BoltDB)
+
+	// saved before conflict detection
+	for i := 0; i < 2; i++ {
+		_, err := tr.SaveTax(context.Background(), &models.Tax{
+			Id:     uuid.NewV1(),
+			Name:   "Basic Sale Tax",
+			Rate:   decimal.NewFromFloat32(10),
+			Origin: models.TaxOriginAll,
+		})
+		assert.NoError(t, err)
+	}
+
+	ts := taxService.NewTaxService(tr, thr, zr, inventoryRepository.NewBoltDBCategoryRepository(db.BoltDB), db.BoltDB, models.TaxCalculationCombined)
+
+	conflicts, err := ts.FindConflicts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(conflicts))
+	assert.Equal(t, models.TaxConflictSameName, conflicts[0].Reason)
+}
+
+func TestTaxService_UpdateTax_WhenTaxMigratedFromLegacyCondition_ThenShouldUpdateTax(t *testing.T) {
+	db := storage.NewTestDB()
+	defer db.Close()
+
+	// saved before conditions validated
+	tax, err := taxRepository.NewBoltDBTaxRepository(db.BoltDB).SaveTax(context.Background(), &models.Tax{
+		Id:         uuid.NewV1(),
+		Name:       "Basic Sale Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.TaxCondition("UNKNOWN"),
+		Categories: map[uuid.UUID]bool{uuid.NewV1(): true},
+	})
+	assert.NoError(t, err)
+
+	tr := taxRepository.NewBoltDBTaxRepository(db.BoltDB)
+	thr := taxRepository.NewBoltDBTaxHistoryRepository(db.BoltDB)
+	zr := taxRepository.NewBoltDBZoneRepository(db.BoltDB)
+
+	ts := taxService.NewTaxService(tr, thr, zr, inventoryRepository.NewBoltDBCategoryRepository(db.BoltDB), db.BoltDB, models.TaxCalculationCombined)
+
+	migrated, err := ts.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err)
+
+	migrated.Rate = decimal.NewFromFloat32(12)
+
+	updated, err := ts.UpdateTax(context.Background(), migrated)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AllCategories, updated.Condition)
+	assert.True(t, updated.Rate.Equal(decimal.NewFromFloat32(12)))
+}
+
+func TestTaxService_GetSaleItem_WhenScopeHasCertificate_ThenShouldSkipCoveredTaxesUntilExpiry(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	federal := &models.Tax{
+		Name:   "Federal Tax",
+		Rate:   decimal.NewFromFloat32(5),
+		Origin: models.TaxOriginAll,
+	}
+	provincial := &models.Tax{
+		Name:   "Provincial Tax",
+		Rate:   decimal.NewFromFloat32(8),
+		Origin: models.TaxOriginAll,
+	}
+
+	federal, err := ts.TaxService.CreateTax(ctx, federal)
+	assert.NoError(t, err)
+	_, err = ts.TaxService.CreateTax(ctx, provincial)
+	assert.NoError(t, err)
+
+	i := &models.InventoryItem{
+		Name:       "Test Item",
+		CategoryId: uuid.NewV1(),
+		Origin:     models.ItemOriginLocal,
+		Price:      decimal.NewFromFloat32(100),
+	}
+
+	now := time.Now()
+	cert := &models.ExemptionCertificate{
+		Id:        "CHR-042",
+		TaxIds:    []uuid.UUID{federal.Id},
+		ExpiresAt: now.Add(time.Hour),
+	}
+
+	si, err := ts.TaxService.GetSaleItem(ctx, i, models.TaxScope{At: now, Certificate: cert})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(8)))
+	assert.Equal(t, 1, len(si.Breakdown))
+
+	cert.ExpiresAt = now
+	si, err = ts.TaxService.GetSaleItem(ctx, i, models.TaxScope{At: now, Certificate: cert})
+	assert.NoError(t, err)
+	assert.True(t, si.Taxes.Equal(decimal.NewFromFloat32(13)))
+}
+
+func TestTaxService_CreateTax_WhenCategoryNotExist_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:       "Basic Sale Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.ExemptToTax,
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true, uuid.NewV1(): true},
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.Equal(t, taxes.ErrInvalidTaxCategory, err)
+}
+
+func TestTaxService_UpdateTax_WhenCategoryNotExist_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:       "Basic Sale Tax",
+		Rate:       decimal.NewFromFloat32(10),
+		Origin:     models.TaxOriginAll,
+		Condition:  models.ExemptToTax,
+		Categories: map[uuid.UUID]bool{ts.newCategoryId(t): true},
+	}
+
+	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	tax.Categories[uuid.NewV1()] = true
+
+	_, err = ts.TaxService.UpdateTax(context.Background(), tax)
+	assert.Equal(t, taxes.ErrInvalidTaxCategory, err)
+}
+
+func TestTaxService_CreateTax_WhenOriginIsMissing_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name: "Test Tax",
+		Rate: decimal.NewFromFloat32(10),
+	}
+
+	_, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.Equal(t, taxes.ErrInvalidTaxOrigin, err)
+}
+
+func TestTaxService_CreateTax_WhenConditionDoesNotMatchCategories_ThenShouldReturnErr(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	cases := map[models.TaxCondition]map[uuid.UUID]bool{
+		"":                   {ts.newCategoryId(t): true},
+		"UNKNOWN":            nil,
+		models.AllCategories: {ts.newCategoryId(t): true},
+	}
+
+	for condition, categories := range cases {
+		tax := &models.Tax{
+			Name:       "Test Tax",
+			Rate:       decimal.NewFromFloat32(10),
+			Origin:     models.TaxOriginAll,
+			Condition:  condition,
+			Categories: categories,
+		}
+
+		_, err := ts.TaxService.CreateTax(context.Background(), tax)
+		assert.Equal(t, taxes.ErrInvalidTaxCondition, err, "unexpected result for condition %q", condition)
+	}
+}
+
+func TestTaxService_CreateTax_WhenConditionAndCategoriesAreMissing_ThenShouldApplyToAllCategories(t *testing.T) {
+	ts := newMockedService()
+	defer ts.Close()
+
+	tax := &models.Tax{
+		Name:   "Test Tax",
+		Rate:   decimal.NewFromFloat32(10),
+		Origin: models.TaxOriginAll,
+	}
+
+	tax, err := ts.TaxService.CreateTax(context.Background(), tax)
+	assert.NoError(t, err)
+
+	find, err := ts.TaxService.GetTaxByID(context.Background(), tax.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AllCategories, find.Condition)
+}
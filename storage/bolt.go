@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	bolt "go.etcd.io/bbolt"
 	"io/ioutil"
@@ -21,6 +22,51 @@ func NewBoltDB(path string) (*BoltDB, error) {
 	return &BoltDB{db}, nil
 }
 
+type txKey struct{}
+
+// RunInTransaction runs fn in a single update transaction. Repositories called with the context given to fn join the
+// transaction, so their changes are committed or rolled back together. Nested calls join the outer transaction.
+func (db *BoltDB) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*bolt.Tx); ok {
+		return fn(ctx)
+	}
+
+	return db.DB.Update(func(tx *bolt.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// UpdateContext runs fn in the transaction carried by context, or in a new update transaction if there is none
+func (db *BoltDB) UpdateContext(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*bolt.Tx); ok {
+		return fn(tx)
+	}
+	return db.DB.Update(fn)
+}
+
+// ViewContext runs fn in the transaction carried by context, or in a new read-only transaction if there is none
+func (db *BoltDB) ViewContext(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*bolt.Tx); ok {
+		return fn(tx)
+	}
+	return db.DB.View(fn)
+}
+
+// InTransaction checks whether context carries a transaction started by RunInTransaction
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*bolt.Tx)
+	return ok
+}
+
+// AfterCommit runs fn once the transaction carried by context is committed, or immediately if there is none
+func AfterCommit(ctx context.Context, fn func()) {
+	if tx, ok := ctx.Value(txKey{}).(*bolt.Tx); ok {
+		tx.OnCommit(fn)
+		return
+	}
+	fn()
+}
+
 type TestDB struct {
 	*BoltDB
 }